@@ -2,56 +2,424 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 	"todo-list-service/pkg/controller"
 	"todo-list-service/pkg/db"
 	"todo-list-service/pkg/env"
+	"todo-list-service/pkg/jwt"
+	"todo-list-service/pkg/logging"
 	"todo-list-service/pkg/middleware"
 	"todo-list-service/pkg/router"
+	"todo-list-service/pkg/tracing"
+	"todo-list-service/pkg/worker"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
+// how often the startup checks whether the migrations other instances are applying are done
+const migrationPollInterval = 2 * time.Second
+
 func main() {
-	cfg, err := env.Load()
+	configFile := flag.String("config", os.Getenv(env.ConfigFileEnv), "a YAML or TOML config file, the environment overrides its settings")
+	printConfig := flag.Bool("print-config", false, "print the effective config with the secrets redacted and exit")
+	flag.Parse()
+
+	cfg, err := env.LoadFile(*configFile)
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
 
-	var uri string
-	if cfg.UseMemoryMongo {
-		mm := db.MockMongo{}
-		uri, err = mm.HostMemoryDb(cfg.MongodPath)
-		if err != nil {
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
 			panic(err)
 		}
-		defer mm.Close()
-	} else {
-		uri = cfg.MongoURL
+		return
 	}
 
-	conn := db.Connection{}
-	err = conn.Connect(uri)
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat == env.LogFormatJSON))
+
+	if args := flag.Args(); len(args) > 0 {
+		if args[0] != "migrate" {
+			fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+			os.Exit(2)
+		}
+		migrateFlags := flag.NewFlagSet("migrate", flag.ExitOnError)
+		dryRun := migrateFlags.Bool("dry-run", false, "only count the documents the missing migrations would change")
+		migrateFlags.Parse(args[1:])
+
+		if cfg.StorageBackend != env.StorageBackendMongo || cfg.UseMemoryMongo {
+			fmt.Fprintln(os.Stderr, "migrate needs STORAGE_BACKEND=mongo and USE_MEMORY_MONGO=false")
+			os.Exit(2)
+		}
+		if err := migrate(cfg.MongoURL, cfg.MongoDatabase, cfg.ItemsCollection, cfg.RevisionsCollection, cfg.MigrationsCollection, *dryRun); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+
+	var tracerProvider trace.TracerProvider = noop.NewTracerProvider()
+	exporter, err := newSpanExporter(cfg.TracesExporter, cfg.OTLPEndpoint, cfg.TracesFile)
 	if err != nil {
 		panic(err)
 	}
-	defer conn.Close()
+	if exporter != nil {
+		provider := tracing.NewProvider(exporter, cfg.TracesService, cfg.TracesSampleRatio, cfg.TracesInterval)
+		tracerProvider = provider
+		// main releases its resources in reverse order once the server shut down:
+		// the trash purger, the mongo client, the memory mongod and last the tracer provider, to export the spans of the others
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+			defer cancel()
+			if err := provider.Shutdown(ctx); err != nil {
+				slog.Error("failed to export the remaining spans", "error", err)
+			}
+		}()
+	}
+
+	var dbHandler db.TodoItemDbHandlerInterface
+	var revisionHandler db.RevisionDbHandlerInterface
+	var listHandler db.TodoListDbHandlerInterface
+	var userHandler db.UserDbHandlerInterface
+	var apiKeyHandler db.ApiKeyDbHandlerInterface
+	var rateLimitHandler db.RateLimitDbHandlerInterface = &db.RateLimitMemoryDbHandler{}
+	// the dependencies the readiness probe checks, the memory backend has none
+	var checks []controller.HealthCheck
+	switch cfg.StorageBackend {
+	case env.StorageBackendMemory:
+		dbHandler = &db.TodoItemMemoryDbHandler{}
+		revisionHandler = &db.RevisionMemoryDbHandler{}
+		listHandler = &db.TodoListMemoryDbHandler{}
+		userHandler = &db.UserMemoryDbHandler{}
+		apiKeyHandler = &db.ApiKeyMemoryDbHandler{}
+	case env.StorageBackendMongo:
+		var uri string
+		if cfg.UseMemoryMongo {
+			mm := db.MockMongo{}
+			uri, err = mm.HostMemoryDb(cfg.MongodPath)
+			if err != nil {
+				panic(err)
+			}
+			defer func() {
+				mm.Close()
+				slog.Info("stopped memory mongo")
+			}()
+		} else {
+			uri = cfg.MongoURL
+		}
+
+		conn := db.Connection{DatabaseName: cfg.MongoDatabase, PoolMonitor: db.PoolMetrics(registry), TracerProvider: tracerProvider}
+		err = conn.Connect(uri)
+		if err != nil {
+			panic(err)
+		}
+		defer func() {
+			conn.Close()
+			slog.Info("disconnected from mongo")
+		}()
+
+		if cfg.MigrateOnStartup {
+			migrator := &db.Migrator{
+				Database:   conn.Database,
+				Migrations: db.Migrations(cfg.ItemsCollection, cfg.RevisionsCollection),
+				Collection: cfg.MigrationsCollection,
+			}
+			// other instances starting at the same time may be applying the migrations
+			if _, err := migrator.RunWaiting(context.TODO(), migrationPollInterval, cfg.MigrationStaleAfter); err != nil {
+				panic(err)
+			}
+		}
+
+		mongoHandler := &db.TodoItemDbHandler{Collection: cfg.ItemsCollection}
+		err = mongoHandler.New(context.TODO(), conn.Database)
+		if err != nil {
+			panic(err)
+		}
+		dbHandler = mongoHandler
+		checks = append(checks,
+			controller.HealthCheck{Name: "mongo", Check: conn.Ping},
+			controller.HealthCheck{Name: "indexes", Check: mongoHandler.CheckIndexes},
+		)
 
-	engine := gin.Default()
+		mongoRevisionHandler := &db.RevisionDbHandler{Collection: cfg.RevisionsCollection}
+		err = mongoRevisionHandler.New(context.TODO(), conn.Database)
+		if err != nil {
+			panic(err)
+		}
+		revisionHandler = mongoRevisionHandler
+
+		mongoListHandler := &db.TodoListDbHandler{Collection: cfg.ListsCollection}
+		err = mongoListHandler.New(context.TODO(), conn.Database)
+		if err != nil {
+			panic(err)
+		}
+		listHandler = mongoListHandler
+
+		mongoUserHandler := &db.UserDbHandler{Collection: cfg.UsersCollection}
+		err = mongoUserHandler.New(context.TODO(), conn.Database)
+		if err != nil {
+			panic(err)
+		}
+		userHandler = mongoUserHandler
+
+		mongoApiKeyHandler := &db.ApiKeyDbHandler{Collection: cfg.ApiKeysCollection}
+		err = mongoApiKeyHandler.New(context.TODO(), conn.Database)
+		if err != nil {
+			panic(err)
+		}
+		apiKeyHandler = mongoApiKeyHandler
+
+		if cfg.RateLimitStore == env.RateLimitStoreMongo {
+			mongoRateLimitHandler := &db.RateLimitDbHandler{Collection: cfg.RateLimitsCollection}
+			err = mongoRateLimitHandler.New(context.TODO(), conn.Database)
+			if err != nil {
+				panic(err)
+			}
+			rateLimitHandler = mongoRateLimitHandler
+		}
+	default:
+		panic(fmt.Errorf("unknown storage backend %q", cfg.StorageBackend))
+	}
+
+	engine := gin.New()
+	// lets the handlers read values the middlewares stored in the request context
+	engine.ContextWithFallback = true
+	// the request span comes first, so the request id and the logs of the other middlewares are part of it
+	engine.Use(otelgin.Middleware(cfg.TracesService, otelgin.WithTracerProvider(tracerProvider)))
+	engine.Use(middleware.RequestId())
+	engine.Use(middleware.AccessLog())
+	engine.Use(middleware.Metrics(registry))
 	engine.Use(middleware.ErrorHandler())
+	engine.Use(gin.Recovery())
+	engine.Use(middleware.Actor())
 
-	dbHandler := &db.TodoItemDbHandler{}
-	err = dbHandler.New(context.TODO(), conn.Database)
-	if err != nil {
-		panic(err)
+	history := &db.TodoItemHistory{
+		TodoItemDbHandlerInterface: db.NewTodoItemMetrics(registry, &db.TodoItemTracing{TodoItemDbHandlerInterface: dbHandler, Tracer: tracerProvider.Tracer("todo-list-service/pkg/db")}, cfg.MetricsItemsRefresh),
+		Revisions:                  revisionHandler,
+	}
+
+	purger := &worker.TrashPurger{
+		TodoItemDbHandler: history,
+		Retention:         cfg.TrashRetention,
+		Interval:          cfg.TrashPurgeInterval,
 	}
+	purgeCtx, stopPurger := context.WithCancel(db.WithActor(context.Background(), "trash-purger"))
+	purgerDone := make(chan struct{})
+	go func() {
+		defer close(purgerDone)
+		purger.Run(purgeCtx)
+	}()
+	defer func() {
+		// a purge that is running is interrupted and waited for
+		stopPurger()
+		<-purgerDone
+		slog.Info("stopped the trash purger")
+	}()
 
 	articleController := &controller.TodoItemController{
-		TodoItemDbHandler:  dbHandler,
+		TodoItemDbHandler:  history,
+		TodoItemHistory:    history,
+		TodoListDbHandler:  listHandler,
+		MaxReturnArraySize: cfg.MaxReturnArraySize,
+		SubtaskDelete:      cfg.SubtaskDelete,
+	}
+
+	listController := &controller.TodoListController{
+		TodoListDbHandler:  listHandler,
+		Items:              articleController,
 		MaxReturnArraySize: cfg.MaxReturnArraySize,
 	}
 
-	router.AttachTodoItemRoutes(engine, articleController)
+	userController := &controller.UserController{
+		UserDbHandler: userHandler,
+	}
+
+	rateLimit := middleware.RateLimit(
+		rateLimitHandler,
+		db.RateLimit{Limit: cfg.RateLimitReads, Window: cfg.RateLimitWindow},
+		db.RateLimit{Limit: cfg.RateLimitWrites, Window: cfg.RateLimitWindow},
+	)
+
+	ipRateLimit := middleware.IPRateLimit(rateLimitHandler, db.RateLimit{Limit: cfg.RateLimitIP, Window: cfg.RateLimitWindow})
+
+	router.AttachMetricsRoutes(engine, registry)
+	health := &controller.HealthController{Checks: checks, Timeout: cfg.HealthCheckTimeout}
+	router.AttachHealthRoutes(engine, health)
+
+	// everything but the registration is scoped to the calling user, who is identified by an api key or otherwise by the auth mode.
+	// Each IP is limited before the authentication, so guessing keys and tokens is limited as well.
+	var users *gin.RouterGroup
+	apiKeys := middleware.ApiKey(apiKeyHandler, userHandler)
+	switch cfg.AuthMode {
+	case env.AuthModeHeader:
+		users = engine.Group("/", ipRateLimit, apiKeys, middleware.User(userHandler))
+		router.AttachRegistrationRoutes(engine.Group("/", ipRateLimit, rateLimit), userController)
+	case env.AuthModeJWT:
+		keys, err := loadKeys(cfg.JWTSecret, cfg.JWTPublicKeyFile, cfg.JWKSFile)
+		if err != nil {
+			panic(err)
+		}
+		verifier := &jwt.Verifier{
+			Keys:     keys,
+			Issuer:   cfg.JWTIssuer,
+			Audience: cfg.JWTAudience,
+			Leeway:   cfg.JWTLeeway,
+		}
+		users = engine.Group("/", ipRateLimit, apiKeys, middleware.JWT(verifier), middleware.TokenUser(userHandler))
+	default:
+		panic(fmt.Errorf("unknown auth mode %q", cfg.AuthMode))
+	}
+
+	// clients are limited on their own once they are identified, and the roles on lists extend to their items so every scoped request needs them
+	users.Use(rateLimit, middleware.ListRoles(listHandler))
+
+	router.AttachUserRoutes(users, userController)
+	router.AttachApiKeyRoutes(users, &controller.ApiKeyController{ApiKeyDbHandler: apiKeyHandler})
+	router.AttachTodoItemRoutes(users, articleController)
+	router.AttachTodoListRoutes(users, listController)
+	router.AttachShareRoutes(users, &controller.ShareController{
+		TodoItemDbHandler: history,
+		TodoListDbHandler: listHandler,
+		UserDbHandler:     userHandler,
+	})
+
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%v", cfg.Port),
+		Handler:      engine,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	serve(server, health, cfg.ShutdownDelay, cfg.ShutdownTimeout)
+}
+
+// serve runs the server until SIGINT or SIGTERM. The readiness probe fails first, so load balancers stop routing traffic to the
+// instance during the delay, then the server stops accepting connections and waits up to the timeout for the requests in flight.
+// A second signal stops the process right away.
+func serve(server *http.Server, health *controller.HealthController, delay, timeout time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	failed := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "address", server.Addr)
+		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
+
+	select {
+	case err := <-failed:
+		// panicking in main still releases the resources, unlike panicking in the goroutine
+		panic(err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	slog.Info("shutting down", "delay", delay, "timeout", timeout)
+	health.ShutDown()
+	time.Sleep(delay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to drain the requests in flight", "error", err)
+		server.Close()
+		return
+	}
+	slog.Info("drained the requests in flight")
+}
 
-	engine.Run(fmt.Sprintf(":%v", cfg.Port))
+// newSpanExporter creates the exporter of TRACES_EXPORTER, or nil when tracing is off
+func newSpanExporter(exporter, endpoint, file string) (sdktrace.SpanExporter, error) {
+	switch exporter {
+	case env.TracesExporterNone:
+		return nil, nil
+	case env.TracesExporterOTLP:
+		return tracing.NewOTLPExporter(context.Background(), endpoint)
+	case env.TracesExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case env.TracesExporterFile:
+		return tracing.NewFileExporter(file)
+	}
+	return nil, fmt.Errorf("unknown traces exporter %q", exporter)
+}
+
+// migrate applies the missing migrations to the database, or only lists them in a dry run
+func migrate(uri, database, items, revisions, migrations string, dryRun bool) error {
+	conn := db.Connection{DatabaseName: database}
+	if err := conn.Connect(uri); err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	migrator := &db.Migrator{Database: conn.Database, Migrations: db.Migrations(items, revisions), Collection: migrations}
+	results, err := migrator.Run(context.Background(), dryRun)
+	for _, result := range results {
+		status := fmt.Sprintf("changed %d documents", result.Documents)
+		switch {
+		case result.Applied:
+			status = "applied before"
+		case dryRun:
+			status = fmt.Sprintf("would change %d documents", result.Documents)
+		}
+		fmt.Printf("%d\t%s\t%s\n", result.Version, result.Description, status)
+	}
+	return err
+}
+
+// loadKeys collects the keys tokens can be signed with from the secret, the PEM file and the JWKS file that are set
+func loadKeys(secret, publicKeyFile, jwksFile string) ([]jwt.Key, error) {
+	keys := []jwt.Key{}
+	if secret != "" {
+		keys = append(keys, jwt.Key{Secret: []byte(secret)})
+	}
+
+	if publicKeyFile != "" {
+		data, err := os.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		public, err := jwt.ParseRSAPublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_RS256_PUBLIC_KEY_FILE: %w", err)
+		}
+		keys = append(keys, jwt.Key{Public: public})
+	}
+
+	if jwksFile != "" {
+		data, err := os.ReadFile(jwksFile)
+		if err != nil {
+			return nil, err
+		}
+		set, err := jwt.ParseJWKS(data)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_JWKS_FILE: %w", err)
+		}
+		keys = append(keys, set...)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("the jwt auth mode needs JWT_HS256_SECRET, JWT_RS256_PUBLIC_KEY_FILE or JWT_JWKS_FILE")
+	}
+	return keys, nil
 }
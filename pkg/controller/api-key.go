@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// api keys look like "todo_<random>", the prefix shown in listings includes a few characters of the random part
+const (
+	apiKeyPrefix       = "todo_"
+	apiKeyPrefixLength = len(apiKeyPrefix) + 6
+)
+
+type ApiKeyController struct {
+	ApiKeyDbHandler db.ApiKeyDbHandlerInterface
+}
+
+type NewApiKeyBody struct {
+	Name  string `json:"name" binding:"required"`
+	Scope string `json:"scope" binding:"required,oneof=read write"`
+}
+
+// ApiKeyBody is the response of issuing and rotating a key, the only time the key itself is shown
+type ApiKeyBody struct {
+	db.ApiKeyDb
+	Key string `json:"key"`
+}
+
+// newApiKey generates a random key, returning it along with the prefix and hash to store
+func newApiKey() (key string, prefix string, hash string, err error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", "", err
+	}
+
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+	return key, key[:apiKeyPrefixLength], db.HashApiKey(key), nil
+}
+
+// rejectApiKey aborts requests made with an api key, keys can only be managed by the user themselves
+func rejectApiKey(c *gin.Context) bool {
+	if _, ok := c.Get("scope"); ok {
+		c.AbortWithError(http.StatusForbidden, fmt.Errorf("api keys can't be managed with an api key"))
+		return true
+	}
+	return false
+}
+
+// Create issues a new key for the calling user
+func (con *ApiKeyController) Create(c *gin.Context) {
+	if rejectApiKey(c) {
+		return
+	}
+
+	body := &NewApiKeyBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	key, prefix, hash, err := newApiKey()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	apiKey := db.ApiKeyDb{
+		Name:      body.Name,
+		Scope:     body.Scope,
+		Prefix:    prefix,
+		Hash:      hash,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if apiKey.Id, err = con.ApiKeyDbHandler.InsertOne(c, &apiKey); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	created, err := con.ApiKeyDbHandler.FindOneById(c, apiKey.Id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, &ApiKeyBody{ApiKeyDb: *created, Key: key})
+}
+
+// FindAll lists the keys of the calling user, without the keys themselves
+func (con *ApiKeyController) FindAll(c *gin.Context) {
+	if rejectApiKey(c) {
+		return
+	}
+
+	keys, err := con.ApiKeyDbHandler.FindAll(c)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+// Rotate replaces the key with a new one, the old key stops working right away
+func (con *ApiKeyController) Rotate(c *gin.Context) {
+	if rejectApiKey(c) {
+		return
+	}
+
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return
+	}
+
+	key, prefix, hash, err := newApiKey()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	rotated, err := con.ApiKeyDbHandler.Rotate(c, id, prefix, hash)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if rotated == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, &ApiKeyBody{ApiKeyDb: *rotated, Key: key})
+}
+
+// Revoke deletes the key
+func (con *ApiKeyController) Revoke(c *gin.Context) {
+	if rejectApiKey(c) {
+		return
+	}
+
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return
+	}
+
+	key, err := con.ApiKeyDbHandler.FindOneById(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if key == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if err := con.ApiKeyDbHandler.DeleteOneById(c, id); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,112 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"todo-list-service/pkg/controller"
+	"todo-list-service/pkg/db"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createApiKey(t *testing.T, engine *gin.Engine, scope string) controller.ApiKeyBody {
+	w := doRequest(engine, http.MethodPost, "/api-keys", gin.H{"name": "CI", "scope": scope})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /api-keys status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	var key controller.ApiKeyBody
+	json.Unmarshal(w.Body.Bytes(), &key)
+	return key
+}
+
+func withApiKey(key string) map[string]string {
+	return map[string]string{"Authorization": "ApiKey " + key}
+}
+
+func TestApiKeyController(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	write := createApiKey(t, engine, db.ApiKeyScopeWrite)
+	read := createApiKey(t, engine, db.ApiKeyScopeRead)
+
+	t.Run("Issues keys", func(t *testing.T) {
+		if !strings.HasPrefix(write.Key, write.Prefix) || write.Owner.Hex() != testUser || write.Scope != db.ApiKeyScopeWrite {
+			t.Errorf("POST /api-keys = %+v", write)
+		}
+
+		w := doRequest(engine, http.MethodPost, "/api-keys", gin.H{"name": "CI", "scope": "admin"})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /api-keys with an unknown scope status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Keys act as their owner within their scope", func(t *testing.T) {
+		item := gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z"}
+		if w := doRequestWithHeaders(engine, http.MethodPost, "/todo", item, withApiKey(write.Key)); w.Code != http.StatusCreated {
+			t.Errorf("POST /todo with a write key status = %d, want %d", w.Code, http.StatusCreated)
+		}
+		if w := doRequestWithHeaders(engine, http.MethodPost, "/todo", item, withApiKey(read.Key)); w.Code != http.StatusForbidden {
+			t.Errorf("POST /todo with a read key status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+
+		var page controller.TodoItemPageBody
+		json.Unmarshal(doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, withApiKey(read.Key)).Body.Bytes(), &page)
+		if len(page.Items) != 1 {
+			t.Errorf("GET /todo with a read key = %v, want the item of the owner", page.Items)
+		}
+
+		if w := doRequestWithHeaders(engine, http.MethodGet, "/api-keys", nil, withApiKey(write.Key)); w.Code != http.StatusForbidden {
+			t.Errorf("GET /api-keys with a key status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+		if w := doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, withApiKey("todo_unknown")); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET /todo with an unknown key status = %d, want %d", w.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("Lists keys with their last use", func(t *testing.T) {
+		var res struct {
+			Keys []map[string]any `json:"keys"`
+		}
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/api-keys", nil).Body.Bytes(), &res)
+		if len(res.Keys) != 2 || res.Keys[0]["lastUsedAt"] == nil || res.Keys[0]["key"] != nil || res.Keys[0]["hash"] != nil {
+			t.Errorf("GET /api-keys = %v, want both keys without secrets", res.Keys)
+		}
+
+		w := doRequestWithHeaders(engine, http.MethodGet, "/api-keys", nil, map[string]string{middleware.UserHeader: otherUser})
+		json.Unmarshal(w.Body.Bytes(), &res)
+		if len(res.Keys) != 0 {
+			t.Errorf("GET /api-keys of another user = %v, want none", res.Keys)
+		}
+	})
+
+	t.Run("Rotates and revokes keys", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/api-keys/"+write.Id.Hex()+"/rotate", nil)
+		var rotated controller.ApiKeyBody
+		json.Unmarshal(w.Body.Bytes(), &rotated)
+		if w.Code != http.StatusOK || rotated.Key == write.Key || rotated.Id != write.Id {
+			t.Fatalf("POST /api-keys/:id/rotate = %d %+v, want a new key", w.Code, rotated)
+		}
+
+		if w := doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, withApiKey(write.Key)); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET /todo with a rotated key status = %d, want %d", w.Code, http.StatusUnauthorized)
+		}
+		if w := doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, withApiKey(rotated.Key)); w.Code != http.StatusOK {
+			t.Errorf("GET /todo with the new key status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		if w := doRequest(engine, http.MethodDelete, "/api-keys/"+write.Id.Hex(), nil); w.Code != http.StatusNoContent {
+			t.Errorf("DELETE /api-keys/:id status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+		if w := doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, withApiKey(rotated.Key)); w.Code != http.StatusUnauthorized {
+			t.Errorf("GET /todo with a revoked key status = %d, want %d", w.Code, http.StatusUnauthorized)
+		}
+		if w := doRequest(engine, http.MethodDelete, "/api-keys/"+write.Id.Hex(), nil); w.Code != http.StatusNotFound {
+			t.Errorf("DELETE /api-keys/:id of a revoked key status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
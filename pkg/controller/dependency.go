@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// errInvalidBlockers is returned when a blocker doesn't exist or would create a dependency cycle
+	errInvalidBlockers = errors.New("invalid blockers")
+	// errBlocked is returned when completing an item whose blockers are still open
+	errBlocked = errors.New("the item is blocked")
+)
+
+// TodoItemPlanBody is the response of the plan endpoint, Truncated is set when there were more than MaxReturnArraySize items
+type TodoItemPlanBody struct {
+	Items     []TodoItemPlanItem `json:"items"`
+	Truncated bool               `json:"truncated"`
+}
+
+// TodoItemPlanItem is an item of the plan, Ready is set when none of its blockers is open anymore
+type TodoItemPlanItem struct {
+	db.TodoItemDb
+	Ready bool `json:"ready"`
+}
+
+// blockerObjectIds converts the blockedBy of a body, which was validated to only contain object ids
+func blockerObjectIds(hexes []string) []primitive.ObjectID {
+	var ids []primitive.ObjectID
+	for _, hex := range hexes {
+		id, _ := primitive.ObjectIDFromHex(hex)
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids
+}
+
+// findByIds returns the items with the given ids that aren't in the trash, without filtering on ids when there are none
+func (con *TodoItemController) findByIds(c *gin.Context, ids []primitive.ObjectID, filter db.TodoItemFilter) ([]db.TodoItemDb, error) {
+	if len(ids) == 0 {
+		return []db.TodoItemDb{}, nil
+	}
+
+	filter.Ids = ids
+	page, err := con.TodoItemDbHandler.FindAll(c, filter, db.PageOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// addedBlockers leaves out the blockers the item is already blocked by. Only the added ones need to be checked,
+// the others were checked when they were added and may since have been trashed or deleted.
+func addedBlockers(current *db.TodoItemDb, blockedBy []primitive.ObjectID) []primitive.ObjectID {
+	if current == nil {
+		return blockedBy
+	}
+
+	var added []primitive.ObjectID
+	for _, blocker := range blockedBy {
+		if !slices.Contains(current.BlockedBy, blocker) {
+			added = append(added, blocker)
+		}
+	}
+	return added
+}
+
+// checkBlockers makes sure the blockers exist and that none of them is blocked by the item itself, directly or transitively.
+// The id of an item that is being created is the nil object id.
+func (con *TodoItemController) checkBlockers(c *gin.Context, id primitive.ObjectID, blockedBy []primitive.ObjectID) error {
+	if slices.Contains(blockedBy, id) {
+		return fmt.Errorf("%w: an item can't block itself", errInvalidBlockers)
+	}
+
+	blockers, err := con.findByIds(c, blockedBy, db.TodoItemFilter{})
+	if err != nil {
+		return err
+	}
+	if len(blockers) != len(blockedBy) {
+		return fmt.Errorf("%w: some of the blockers don't exist", errInvalidBlockers)
+	}
+
+	visited := map[primitive.ObjectID]bool{}
+	for len(blockers) > 0 {
+		next := []primitive.ObjectID{}
+		for _, blocker := range blockers {
+			for _, transitive := range blocker.BlockedBy {
+				if transitive == id {
+					return fmt.Errorf("%w: the blockers depend on the item, which would create a cycle", errInvalidBlockers)
+				}
+				if !visited[transitive] {
+					visited[transitive] = true
+					next = append(next, transitive)
+				}
+			}
+		}
+
+		if blockers, err = con.findByIds(c, next, db.TodoItemFilter{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkCompletion refuses to complete an item while any of its blockers is open, unless the "force" query param is set.
+// Blockers that are in the trash or were deleted don't block anymore.
+func (con *TodoItemController) checkCompletion(c *gin.Context, wasCompleted bool, body *NewTodoItemBody) error {
+	if !body.Completed || wasCompleted || c.Query("force") == "true" {
+		return nil
+	}
+
+	open := false
+	blockers, err := con.findByIds(c, blockerObjectIds(body.BlockedBy), db.TodoItemFilter{Completed: &open})
+	if err != nil {
+		return err
+	}
+
+	if len(blockers) > 0 {
+		ids := make([]string, len(blockers))
+		for i, blocker := range blockers {
+			ids[i] = blocker.Id.Hex()
+		}
+		return fmt.Errorf("%w by the open items %s, complete them first or set force=true", errBlocked, strings.Join(ids, ", "))
+	}
+	return nil
+}
+
+// dependencyStatus is the status to respond with for errors of checkBlockers and checkCompletion,
+// invalidStatus is used for invalid blockers
+func dependencyStatus(err error, invalidStatus int) int {
+	switch {
+	case errors.Is(err, errInvalidBlockers):
+		return invalidStatus
+	case errors.Is(err, errBlocked):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// Blockers lists the items the item is blocked by, it accepts the same query params as FindAll
+func (con *TodoItemController) Blockers(c *gin.Context) {
+	item := con.findItem(c)
+	if item == nil {
+		return
+	}
+
+	filter, err := todoItemFilter(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if len(item.BlockedBy) == 0 {
+		c.JSON(http.StatusOK, &TodoItemPageBody{Items: []TodoItemBody{}})
+		return
+	}
+	filter.Ids = item.BlockedBy
+
+	con.findPage(c, filter)
+}
+
+// Dependents lists the items that are blocked by the item, it accepts the same query params as FindAll
+func (con *TodoItemController) Dependents(c *gin.Context) {
+	item := con.findItem(c)
+	if item == nil {
+		return
+	}
+
+	filter, err := todoItemFilter(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	filter.BlockedBy = []primitive.ObjectID{item.Id}
+
+	con.findPage(c, filter)
+}
+
+// Plan lists the open items in an order they can be worked on, every item comes after its open blockers
+// and otherwise the earliest due date goes first. Items of archived lists are left out. The "ready" query param only lists the items that can be worked on now.
+func (con *TodoItemController) Plan(c *gin.Context) {
+	readyOnly := false
+	if readyString, ok := c.GetQuery("ready"); ok {
+		var err error
+		if readyOnly, err = strconv.ParseBool(readyString); err != nil {
+			c.AbortWithError(http.StatusBadRequest, fmt.Errorf("ready must be a boolean"))
+			return
+		}
+	}
+
+	open := false
+	filter := db.TodoItemFilter{Completed: &open}
+	if err := con.excludeArchived(c, &filter); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	page, err := con.TodoItemDbHandler.FindAll(c, filter, db.PageOptions{})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	body := &TodoItemPlanBody{Items: []TodoItemPlanItem{}}
+	for _, item := range topologicalOrder(page.Items) {
+		if readyOnly && !item.Ready {
+			continue
+		}
+		if len(body.Items) == con.MaxReturnArraySize {
+			body.Truncated = true
+			break
+		}
+		body.Items = append(body.Items, item)
+	}
+
+	c.JSON(http.StatusOK, body)
+}
+
+// topologicalOrder sorts the open items so blockers come before the items they block, using Kahn's algorithm.
+// Blockers that aren't part of the open items don't count, items left in a cycle go last.
+func topologicalOrder(items []db.TodoItemDb) []TodoItemPlanItem {
+	byId := map[primitive.ObjectID]*db.TodoItemDb{}
+	for i := range items {
+		byId[items[i].Id] = &items[i]
+	}
+
+	blocking := map[primitive.ObjectID]int{}
+	dependents := map[primitive.ObjectID][]primitive.ObjectID{}
+	for _, item := range items {
+		for _, blocker := range item.BlockedBy {
+			if byId[blocker] != nil {
+				blocking[item.Id]++
+				dependents[blocker] = append(dependents[blocker], item.Id)
+			}
+		}
+	}
+
+	available := &byDueDate{}
+	for i := range items {
+		if blocking[items[i].Id] == 0 {
+			heap.Push(available, &items[i])
+		}
+	}
+
+	ordered := []TodoItemPlanItem{}
+	done := map[primitive.ObjectID]bool{}
+	for available.Len() > 0 {
+		item := heap.Pop(available).(*db.TodoItemDb)
+		done[item.Id] = true
+		ordered = append(ordered, TodoItemPlanItem{TodoItemDb: *item, Ready: countOpen(item, byId) == 0})
+
+		for _, dependent := range dependents[item.Id] {
+			blocking[dependent]--
+			if blocking[dependent] == 0 {
+				heap.Push(available, byId[dependent])
+			}
+		}
+	}
+
+	for _, item := range items {
+		if !done[item.Id] {
+			ordered = append(ordered, TodoItemPlanItem{TodoItemDb: item})
+		}
+	}
+	return ordered
+}
+
+// countOpen counts the blockers of the item that are still open
+func countOpen(item *db.TodoItemDb, open map[primitive.ObjectID]*db.TodoItemDb) int {
+	count := 0
+	for _, blocker := range item.BlockedBy {
+		if open[blocker] != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// byDueDate is a heap of items ordered by due date and then by id
+type byDueDate []*db.TodoItemDb
+
+func (h byDueDate) Len() int { return len(h) }
+func (h byDueDate) Less(i, j int) bool {
+	if !h[i].DueDate.Equal(h[j].DueDate) {
+		return h[i].DueDate.Before(h[j].DueDate)
+	}
+	return h[i].Id.Hex() < h[j].Id.Hex()
+}
+func (h byDueDate) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *byDueDate) Push(x any)   { *h = append(*h, x.(*db.TodoItemDb)) }
+func (h *byDueDate) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
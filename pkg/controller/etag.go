@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag is the strong entity tag of the current version of the item
+func etag(item *db.TodoItemDb) string {
+	return strconv.Quote(strconv.FormatInt(item.Version, 10))
+}
+
+// respondWithItem sends the item along with its ETag
+func respondWithItem(c *gin.Context, status int, item *db.TodoItemDb) {
+	c.Header("ETag", etag(item))
+	c.JSON(status, item)
+}
+
+// ifMatchVersion reads the version an update or delete is conditional on from the If-Match header.
+// Without the header, or with "*", any version matches. The error means the header can never match.
+func ifMatchVersion(c *gin.Context) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return db.AnyVersion, nil
+	}
+
+	if strings.Contains(header, ",") {
+		return 0, fmt.Errorf("If-Match only supports a single entity tag")
+	}
+
+	// weak tags never match with the strong comparison If-Match requires
+	tag, err := strconv.Unquote(header)
+	if err != nil {
+		return 0, fmt.Errorf("If-Match must be an entity tag returned by the service")
+	}
+
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("If-Match must be an entity tag returned by the service")
+	}
+
+	return version, nil
+}
+
+// ifNoneMatch tells whether the If-None-Match header matches the item, using the weak comparison
+func ifNoneMatch(c *gin.Context, item *db.TodoItemDb) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	current := etag(item)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the query params understood by the list endpoints
+var listQueryParams = map[string]bool{
+	"completed":  true,
+	"dueBefore":  true,
+	"dueAfter":   true,
+	"label":      true,
+	"labelMatch": true,
+	"q":          true,
+	"sort":       true,
+	"limit":      true,
+	"after":      true,
+	"expandFrom": true,
+	"expandTo":   true,
+}
+
+// todoItemFilter reads the filter query params, unknown params are rejected so typos don't silently return everything
+//
+//	completed=false          only (un)completed items
+//	dueBefore=<RFC 3339>     items due before the given time
+//	dueAfter=<RFC 3339>      items due after the given time
+//	label=a,b                items with any of the labels
+//	labelMatch=all           items with all of the labels instead
+//	q=groceries              items whose title contains the text, case-insensitive
+func todoItemFilter(c *gin.Context) (db.TodoItemFilter, error) {
+	filter := db.TodoItemFilter{}
+
+	for param := range c.Request.URL.Query() {
+		if !listQueryParams[param] {
+			return filter, fmt.Errorf("unknown query parameter %q", param)
+		}
+	}
+
+	if completedString, ok := c.GetQuery("completed"); ok {
+		completed, err := strconv.ParseBool(completedString)
+		if err != nil {
+			return filter, fmt.Errorf("completed must be a boolean")
+		}
+		filter.Completed = &completed
+	}
+
+	for param, bound := range map[string]**time.Time{"dueBefore": &filter.DueBefore, "dueAfter": &filter.DueAfter} {
+		if dateString, ok := c.GetQuery(param); ok {
+			date, err := time.Parse(time.RFC3339, dateString)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC 3339 date", param)
+			}
+			*bound = &date
+		}
+	}
+
+	if labels := c.Query("label"); labels != "" {
+		filter.Labels = strings.Split(labels, ",")
+	}
+
+	switch c.DefaultQuery("labelMatch", "any") {
+	case "any":
+	case "all":
+		filter.MatchAllLabels = true
+	default:
+		return filter, fmt.Errorf("labelMatch must be either any or all")
+	}
+
+	filter.Title = c.Query("q")
+	return filter, nil
+}
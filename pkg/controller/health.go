@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the statuses of the health report and its checks
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+// HealthCheck is a dependency the service needs to serve requests, like the database
+type HealthCheck struct {
+	Name  string
+	Check func(context.Context) error
+}
+
+// HealthController answers the probes of load balancers and orchestrators
+type HealthController struct {
+	Checks []HealthCheck
+	// Timeout bounds how long each check may take, slower checks fail
+	Timeout time.Duration
+
+	shuttingDown atomic.Bool
+}
+
+type HealthBody struct {
+	Status string `json:"status"`
+	// ShuttingDown is true once the service stopped accepting traffic
+	ShuttingDown bool                       `json:"shuttingDown,omitempty"`
+	Checks       map[string]CheckResultBody `json:"checks,omitempty"`
+}
+
+// CheckResultBody is the outcome of a check, its error is only logged since the probes aren't authenticated
+type CheckResultBody struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+// ShutDown makes the service report that it isn't ready anymore, so no new traffic is routed to it
+func (con *HealthController) ShutDown() {
+	con.shuttingDown.Store(true)
+}
+
+// Live responds as long as the process is able to serve requests at all
+func (con *HealthController) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthBody{Status: HealthUp})
+}
+
+// Ready responds with a 503 while a dependency is unavailable or the service is shutting down
+func (con *HealthController) Ready(c *gin.Context) {
+	report := con.report(c)
+	c.JSON(healthStatus(report), HealthBody{Status: report.Status, ShuttingDown: report.ShuttingDown})
+}
+
+// Health responds like Ready with the status and latency of each dependency, the errors of failed checks are logged
+func (con *HealthController) Health(c *gin.Context) {
+	report := con.report(c)
+	c.JSON(healthStatus(report), report)
+}
+
+// report runs the checks concurrently, each bounded by the timeout
+func (con *HealthController) report(context context.Context) HealthBody {
+	report := HealthBody{Status: HealthUp, ShuttingDown: con.shuttingDown.Load(), Checks: map[string]CheckResultBody{}}
+
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, check := range con.Checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			result := runCheck(context, check, con.Timeout)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status == HealthDown {
+				report.Status = HealthDown
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	if report.ShuttingDown {
+		report.Status = HealthDown
+	}
+	return report
+}
+
+func runCheck(ctx context.Context, check HealthCheck, timeout time.Duration) CheckResultBody {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	result := CheckResultBody{Status: HealthUp, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = HealthDown
+		slog.WarnContext(ctx, "health check failed", "check", check.Name, "error", err)
+	}
+	return result
+}
+
+func healthStatus(report HealthBody) int {
+	if report.Status == HealthDown {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
@@ -0,0 +1,87 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"todo-list-service/pkg/controller"
+	"todo-list-service/pkg/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHealthController(t *testing.T) {
+	t.Parallel()
+
+	newEngine := func(ctrl *controller.HealthController) *gin.Engine {
+		gin.SetMode(gin.TestMode)
+		engine := gin.New()
+		router.AttachHealthRoutes(engine, ctrl)
+		return engine
+	}
+	up := controller.HealthCheck{Name: "mongo", Check: func(ctx context.Context) error { return nil }}
+	slow := controller.HealthCheck{Name: "indexes", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	t.Run("Ready while the dependencies are up", func(t *testing.T) {
+		engine := newEngine(&controller.HealthController{Checks: []controller.HealthCheck{up}, Timeout: time.Second})
+
+		for _, path := range []string{"/healthz", "/readyz", "/health"} {
+			if w := doRequest(engine, http.MethodGet, path, nil); w.Code != http.StatusOK {
+				t.Errorf("GET %s status = %d, want %d", path, w.Code, http.StatusOK)
+			}
+		}
+	})
+
+	t.Run("Not ready when a dependency times out", func(t *testing.T) {
+		engine := newEngine(&controller.HealthController{Checks: []controller.HealthCheck{up, slow}, Timeout: 10 * time.Millisecond})
+
+		if w := doRequest(engine, http.MethodGet, "/readyz", nil); w.Code != http.StatusServiceUnavailable {
+			t.Errorf("GET /readyz status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+		}
+		if w := doRequest(engine, http.MethodGet, "/healthz", nil); w.Code != http.StatusOK {
+			t.Errorf("GET /healthz status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var report controller.HealthBody
+		w := doRequest(engine, http.MethodGet, "/health", nil)
+		json.Unmarshal(w.Body.Bytes(), &report)
+		if w.Code != http.StatusServiceUnavailable || report.Status != controller.HealthDown {
+			t.Errorf("GET /health = %d %+v, want %d and down", w.Code, report, http.StatusServiceUnavailable)
+		}
+		if report.Checks["mongo"].Status != controller.HealthUp || report.Checks["indexes"].Status != controller.HealthDown {
+			t.Errorf("GET /health checks = %+v, want mongo up and indexes timed out", report.Checks)
+		}
+	})
+
+	t.Run("Not ready while shutting down", func(t *testing.T) {
+		ctrl := &controller.HealthController{Checks: []controller.HealthCheck{up}, Timeout: time.Second}
+		engine := newEngine(ctrl)
+		ctrl.ShutDown()
+
+		var report controller.HealthBody
+		w := doRequest(engine, http.MethodGet, "/readyz", nil)
+		json.Unmarshal(w.Body.Bytes(), &report)
+		if w.Code != http.StatusServiceUnavailable || !report.ShuttingDown {
+			t.Errorf("GET /readyz = %d %+v, want %d while shutting down", w.Code, report, http.StatusServiceUnavailable)
+		}
+	})
+
+	t.Run("Hides the error of a failed check", func(t *testing.T) {
+		failed := controller.HealthCheck{Name: "mongo", Check: func(ctx context.Context) error { return errors.New("dial tcp db.internal:27017: connection refused") }}
+		engine := newEngine(&controller.HealthController{Checks: []controller.HealthCheck{failed}, Timeout: time.Second})
+
+		w := doRequest(engine, http.MethodGet, "/health", nil)
+		var report controller.HealthBody
+		json.Unmarshal(w.Body.Bytes(), &report)
+		if report.Checks["mongo"].Status != controller.HealthDown || strings.Contains(w.Body.String(), "db.internal") {
+			t.Errorf("GET /health = %s, want mongo down without its error", w.Body)
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// History responds with all revisions of the item, oldest first. It keeps working after the item is deleted.
+func (con *TodoItemController) History(c *gin.Context) {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return
+	}
+
+	revisions, err := con.TodoItemHistory.FindRevisions(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if len(revisions) == 0 {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// Restore rolls the item back to the state of the "revision" query param, which also brings back deleted items.
+// The revision has to pass the same checks of its parent, list and blockers as an update, otherwise the restore responds with a 409.
+// The If-Match header makes the restore conditional on the current version of the item.
+func (con *TodoItemController) Restore(c *gin.Context) {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return
+	}
+
+	revision, err := strconv.ParseInt(c.Query("revision"), 10, 64)
+	if err != nil || revision < 0 {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("revision must be a non-negative integer"))
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
+
+	// deleted items can only be restored by their owner, which the history checks
+	if !con.authorize(c, id, db.RoleEditor) {
+		return
+	}
+
+	if err := con.checkRestore(c, id, revision); err != nil {
+		c.AbortWithError(restoreStatus(err), err)
+		return
+	}
+
+	item, err := con.TodoItemHistory.Restore(c, id, revision, version)
+	if errors.Is(err, db.ErrRevisionNotFound) {
+		c.AbortWithError(http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, db.ErrVersionConflict) {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithItem(c, http.StatusOK, item)
+}
+
+// checkRestore runs the checks of an update on the revision the item is restored to, against the current state of the other items and lists
+func (con *TodoItemController) checkRestore(c *gin.Context, id primitive.ObjectID, revision int64) error {
+	source, err := con.TodoItemHistory.FindRevision(c, id, revision)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return db.ErrRevisionNotFound
+	}
+	restored := &source.Item
+
+	current, err := con.TodoItemDbHandler.FindOneById(c, id)
+	if err != nil {
+		return err
+	}
+
+	// items can stay in an archived list, but coming back from the trash or from being deleted adds them to it again
+	if current != nil && !reflect.DeepEqual(current.ListId, restored.ListId) {
+		err = con.checkMove(c, current, restored.ListId)
+	} else if current == nil || current.DeletedAt != nil {
+		err = con.checkList(c, restored.ListId)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := con.checkBlockers(c, id, addedBlockers(current, restored.BlockedBy)); err != nil {
+		return err
+	}
+
+	blockedBy := make([]string, len(restored.BlockedBy))
+	for i, blocker := range restored.BlockedBy {
+		blockedBy[i] = blocker.Hex()
+	}
+	wasCompleted := current != nil && current.Completed
+	if err := con.checkCompletion(c, wasCompleted, &NewTodoItemBody{Completed: restored.Completed, BlockedBy: blockedBy}); err != nil {
+		return err
+	}
+
+	return con.checkParent(c, id, restored.ParentId)
+}
+
+// restoreStatus is the status to respond with for errors of checkRestore, a revision that doesn't fit the current state is a conflict
+func restoreStatus(err error) int {
+	switch {
+	case errors.Is(err, db.ErrRevisionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, errInvalidList), errors.Is(err, errArchivedList),
+		errors.Is(err, errInvalidBlockers), errors.Is(err, errBlocked), errors.Is(err, errInvalidParent):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
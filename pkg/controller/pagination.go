@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TodoItemPageBody is the response of the list endpoints, clients pass Next as the "after" query param
+// to fetch the following page as long as Truncated is set
+type TodoItemPageBody struct {
+	Items     []TodoItemBody `json:"items"`
+	Next      string         `json:"next,omitempty"`
+	Truncated bool           `json:"truncated"`
+}
+
+// TodoItemBody is an item as it is returned by the read endpoints. Progress is only set for items with children,
+// Occurrences only when expanding occurrences is requested.
+type TodoItemBody struct {
+	db.TodoItemDb
+	Progress    *db.ChildCount `json:"progress,omitempty"`
+	Occurrences []time.Time    `json:"occurrences,omitempty"`
+}
+
+// pageOptions reads the "sort", "limit" and "after" query params, the limit defaults to and is capped by MaxReturnArraySize
+func (con *TodoItemController) pageOptions(c *gin.Context) (db.PageOptions, error) {
+	page := db.PageOptions{Limit: con.MaxReturnArraySize}
+
+	sort, err := db.ParseSort(c.Query("sort"))
+	if err != nil {
+		return page, err
+	}
+	page.Sort = sort
+
+	if limitString, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(limitString)
+		if err != nil || limit <= 0 {
+			return page, fmt.Errorf("limit must be a positive integer")
+		}
+		page.Limit = min(limit, con.MaxReturnArraySize)
+	}
+
+	if token := c.Query("after"); token != "" {
+		after, err := db.ParsePageToken(token, page.Sort)
+		if err != nil {
+			return page, err
+		}
+		page.After = after
+	}
+
+	return page, nil
+}
+
+func newTodoItemPageBody(page *db.TodoItemPage) (*TodoItemPageBody, error) {
+	body := &TodoItemPageBody{Items: make([]TodoItemBody, len(page.Items)), Truncated: page.Truncated}
+	for i, item := range page.Items {
+		body.Items[i].TodoItemDb = item
+	}
+
+	if page.Next != nil {
+		next, err := page.Next.Token()
+		if err != nil {
+			return nil, err
+		}
+		body.Next = next
+	}
+
+	return body, nil
+}
@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+	"todo-list-service/pkg/db"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JsonPatchContentType  = "application/json-patch+json"
+)
+
+// todoItemDocument is the representation of an item that patches are applied to.
+// Every field is always present, so JSON Patch paths like "/labels/-" resolve.
+type todoItemDocument struct {
+	Title string `json:"title"`
+	// LegacyTitle repeats the title, so patches may change either of its names
+	LegacyTitle string    `json:"string"`
+	DueDate     time.Time `json:"dueDate"`
+	Labels      []string  `json:"labels"`
+	Description string    `json:"description"`
+	Completed   bool      `json:"completed"`
+	Recurrence  string    `json:"recurrence"`
+	ParentId    string    `json:"parentId"`
+	ListId      string    `json:"listId"`
+	BlockedBy   []string  `json:"blockedBy"`
+}
+
+func newTodoItemDocument(item *db.TodoItemDb) todoItemDocument {
+	labels := item.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+
+	parentId := ""
+	if item.ParentId != nil {
+		parentId = item.ParentId.Hex()
+	}
+
+	listId := ""
+	if item.ListId != nil {
+		listId = item.ListId.Hex()
+	}
+
+	blockedBy := []string{}
+	for _, id := range item.BlockedBy {
+		blockedBy = append(blockedBy, id.Hex())
+	}
+
+	return todoItemDocument{
+		Title:       item.Title,
+		LegacyTitle: item.Title,
+		DueDate:     item.DueDate,
+		Labels:      labels,
+		Description: item.Description,
+		Completed:   item.Completed,
+		Recurrence:  item.Recurrence,
+		ParentId:    parentId,
+		ListId:      listId,
+		BlockedBy:   blockedBy,
+	}
+}
+
+// PatchByID applies a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902) to the item, depending on the content type.
+// The patched item has to pass the same validation as a created item, after which only the changed fields are stored.
+// The If-Match header makes the patch conditional on the version of the item.
+func (con *TodoItemController) PatchByID(c *gin.Context) {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return
+	}
+
+	contentType := c.ContentType()
+	if contentType != MergePatchContentType && contentType != JsonPatchContentType {
+		c.AbortWithError(http.StatusUnsupportedMediaType, fmt.Errorf("content type must be %s or %s", MergePatchContentType, JsonPatchContentType))
+		return
+	}
+
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
+
+	item, err := con.TodoItemDbHandler.FindOneById(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if item == nil || item.DeletedAt != nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if !allowed(c, db.ItemRole(c, item), db.RoleEditor) {
+		return
+	}
+
+	if version != db.AnyVersion && version != item.Version {
+		c.AbortWithError(http.StatusPreconditionFailed, db.ErrVersionConflict)
+		return
+	}
+
+	original := newTodoItemDocument(item)
+	doc, err := json.Marshal(original)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	patched, status, err := applyPatch(contentType, doc, patch)
+	if err != nil {
+		c.AbortWithError(status, err)
+		return
+	}
+
+	body := &NewTodoItemBody{}
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(body); err != nil {
+		c.AbortWithError(http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	// the title is resolved first, so removing it fails the validation even though the legacy name is still present
+	body.resolveTitle(original.Title)
+	if err := binding.Validator.ValidateStruct(body); err != nil {
+		c.AbortWithError(http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	if !slices.Equal(body.BlockedBy, original.BlockedBy) {
+		if err := con.checkBlockers(c, id, addedBlockers(item, blockerObjectIds(body.BlockedBy))); err != nil {
+			c.AbortWithError(dependencyStatus(err, http.StatusUnprocessableEntity), err)
+			return
+		}
+	}
+	if err := con.checkCompletion(c, original.Completed, body); err != nil {
+		c.AbortWithError(dependencyStatus(err, http.StatusUnprocessableEntity), err)
+		return
+	}
+
+	if err := body.applyRecurrence(); err != nil {
+		c.AbortWithError(http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	update := todoItemUpdate(&original, body)
+	if update.ParentId != nil {
+		if err := con.checkParent(c, id, parentObjectId(body.ParentId)); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, errInvalidParent) {
+				status = http.StatusUnprocessableEntity
+			}
+			c.AbortWithError(status, err)
+			return
+		}
+	}
+
+	if update.ListId != nil {
+		if err := con.checkMove(c, item, listObjectId(body.ListId)); err != nil {
+			c.AbortWithError(listStatus(err, http.StatusUnprocessableEntity), err)
+			return
+		}
+	}
+
+	if update.Empty() {
+		respondWithItem(c, http.StatusOK, item)
+		return
+	}
+
+	// the patch was computed from the version that was read, so the write must not apply to any other version
+	item, err = con.TodoItemDbHandler.PatchOneById(c, id, update, item.Version)
+	if errors.Is(err, db.ErrVersionConflict) {
+		status := http.StatusConflict
+		if version != db.AnyVersion {
+			status = http.StatusPreconditionFailed
+		}
+		c.AbortWithError(status, err)
+		return
+	}
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if item == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	respondWithItem(c, http.StatusOK, item)
+}
+
+// applyPatch returns the patched document, or the status code and error to respond with
+func applyPatch(contentType string, doc, patch []byte) ([]byte, int, error) {
+	if contentType == MergePatchContentType {
+		patched, err := jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return nil, http.StatusBadRequest, err
+		}
+		return patched, 0, nil
+	}
+
+	operations, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	patched, err := operations.Apply(doc)
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return nil, http.StatusConflict, err
+	}
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, err
+	}
+
+	return patched, 0, nil
+}
+
+// todoItemUpdate only contains the fields that differ between the original and the patched item
+func todoItemUpdate(original *todoItemDocument, patched *NewTodoItemBody) *db.TodoItemUpdate {
+	update := &db.TodoItemUpdate{}
+
+	if patched.Title != original.Title {
+		update.Title = &patched.Title
+	}
+	if !patched.DueDate.Equal(original.DueDate) {
+		update.DueDate = &patched.DueDate
+	}
+	if !slices.Equal(patched.Labels, original.Labels) {
+		update.Labels = &patched.Labels
+		if patched.Labels == nil {
+			update.Labels = &[]string{}
+		}
+	}
+	if patched.Description != original.Description {
+		update.Description = &patched.Description
+	}
+	if patched.Completed != original.Completed {
+		update.Completed = &patched.Completed
+	}
+	if patched.Recurrence != original.Recurrence {
+		update.Recurrence = &patched.Recurrence
+	}
+	if !slices.Equal(patched.BlockedBy, original.BlockedBy) {
+		blockedBy := blockerObjectIds(patched.BlockedBy)
+		update.BlockedBy = &blockedBy
+	}
+	if patched.ParentId != original.ParentId {
+		update.ParentId = &primitive.NilObjectID
+		if parentId := parentObjectId(patched.ParentId); parentId != nil {
+			update.ParentId = parentId
+		}
+	}
+
+	if patched.ListId != original.ListId {
+		update.ListId = &primitive.NilObjectID
+		if listId := listObjectId(patched.ListId); listId != nil {
+			update.ListId = listId
+		}
+	}
+
+	return update
+}
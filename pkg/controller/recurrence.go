@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"fmt"
+	"time"
+	"todo-list-service/pkg/db"
+	"todo-list-service/pkg/recurrence"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxExpandWindow is the longest time range occurrences can be expanded in, longer ones would cost too many iterations per item
+const maxExpandWindow = 366 * 24 * time.Hour
+
+// expandWindow is the time range the occurrences of the listed items are expanded in
+type expandWindow struct {
+	From time.Time
+	To   time.Time
+}
+
+// applyRecurrence validates the recurrence of the body and brings it into canonical form. A recurring item
+// that gets completed moves on to its next occurrence instead, until its series ends.
+// Occurrences are computed in UTC, the time zone due dates are stored in.
+func (b *NewTodoItemBody) applyRecurrence() error {
+	if b.Recurrence == "" {
+		return nil
+	}
+
+	rule, err := recurrence.Parse(b.Recurrence)
+	if err != nil {
+		return fmt.Errorf("invalid recurrence: %w", err)
+	}
+	b.Recurrence = rule.String()
+
+	if !b.Completed {
+		return nil
+	}
+
+	if next, rest, ok := rule.Next(b.DueDate.UTC()); ok {
+		b.DueDate = next
+		b.Recurrence = rest.String()
+		b.Completed = false
+	}
+	return nil
+}
+
+// expandWindowParams reads the "expandFrom" and "expandTo" query params, the window is nil when they are not given
+func expandWindowParams(c *gin.Context) (*expandWindow, error) {
+	fromString, hasFrom := c.GetQuery("expandFrom")
+	toString, hasTo := c.GetQuery("expandTo")
+	if !hasFrom && !hasTo {
+		return nil, nil
+	}
+	if !hasFrom || !hasTo {
+		return nil, fmt.Errorf("expandFrom and expandTo must be given together")
+	}
+
+	from, err := time.Parse(time.RFC3339, fromString)
+	if err != nil {
+		return nil, fmt.Errorf("expandFrom must be an RFC 3339 date")
+	}
+	to, err := time.Parse(time.RFC3339, toString)
+	if err != nil {
+		return nil, fmt.Errorf("expandTo must be an RFC 3339 date")
+	}
+	if !to.After(from) {
+		return nil, fmt.Errorf("expandTo must be after expandFrom")
+	}
+	if to.Sub(from) > maxExpandWindow {
+		return nil, fmt.Errorf("expandTo can be at most 366 days after expandFrom")
+	}
+
+	return &expandWindow{From: from, To: to}, nil
+}
+
+// occurrences lists the due dates of the item within the window, at most MaxReturnArraySize of them
+func (con *TodoItemController) occurrences(item *db.TodoItemDb, window *expandWindow) ([]time.Time, error) {
+	if item.Recurrence == "" {
+		if item.DueDate.Before(window.From) || !item.DueDate.Before(window.To) {
+			return []time.Time{}, nil
+		}
+		return []time.Time{item.DueDate}, nil
+	}
+
+	rule, err := recurrence.Parse(item.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	return rule.Between(item.DueDate, window.From, window.To, con.MaxReturnArraySize), nil
+}
@@ -0,0 +1,331 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errForbidden is returned when the role of the user on an item or list doesn't allow an action
+var errForbidden = errors.New("forbidden")
+
+// ShareController manages who items and lists are shared with. Everyone with access can see the shares,
+// changing them takes the owner role, except for users leaving something that was shared with them.
+type ShareController struct {
+	TodoItemDbHandler db.TodoItemDbHandlerInterface
+	TodoListDbHandler db.TodoListDbHandlerInterface
+	UserDbHandler     db.UserDbHandlerInterface
+}
+
+type NewShareBody struct {
+	UserId string `json:"userId" binding:"required,mongodb"`
+	Role   string `json:"role" binding:"required,oneof=viewer editor owner"`
+}
+
+type RoleBody struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor owner"`
+}
+
+// SharesBody lists who has access to an item or list
+type SharesBody struct {
+	Owner  primitive.ObjectID `json:"owner"`
+	Shares []db.Share         `json:"shares"`
+}
+
+// shared is an item or list as far as sharing is concerned, along with the role of the calling user on it
+type shared struct {
+	owner  primitive.ObjectID
+	shares []db.Share
+	role   string
+}
+
+// shareStore gives the share endpoints access to either items or lists, the functions return nil when there is no such item or list
+type shareStore struct {
+	find   func(c *gin.Context, id primitive.ObjectID) (*shared, error)
+	set    func(c *gin.Context, id primitive.ObjectID, share db.Share) (*shared, error)
+	remove func(c *gin.Context, id primitive.ObjectID, user primitive.ObjectID) (*shared, error)
+}
+
+// allowed aborts the request with a 403 when the role doesn't allow what the required role allows
+func allowed(c *gin.Context, role string, required string) bool {
+	if db.RoleAllows(role, required) {
+		return true
+	}
+
+	c.AbortWithError(http.StatusForbidden, fmt.Errorf("%w: this needs the %s role", errForbidden, required))
+	return false
+}
+
+// authorize reads the item and aborts the request with a 403 when the role of the user on it doesn't allow the action.
+// Items that don't exist are left to the handler.
+func (con *TodoItemController) authorize(c *gin.Context, id primitive.ObjectID, required string) bool {
+	item, err := con.TodoItemDbHandler.FindOneById(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return false
+	}
+
+	return item == nil || allowed(c, db.ItemRole(c, item), required)
+}
+
+// allowedIds splits the ids of bulk changes into the items the role of the user allows the change on, the ids that
+// don't exist and the ids of the items the role of the user doesn't allow it on
+func (con *TodoItemController) allowedIds(c *gin.Context, ids []primitive.ObjectID, required string) (allowed []primitive.ObjectID, missing []string, forbidden []string, err error) {
+	page, err := con.TodoItemDbHandler.FindAll(c, db.TodoItemFilter{Ids: ids}, db.PageOptions{})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	roles := map[primitive.ObjectID]string{}
+	for _, item := range page.Items {
+		roles[item.Id] = db.ItemRole(c, &item)
+	}
+
+	allowed, missing, forbidden = []primitive.ObjectID{}, []string{}, []string{}
+	for _, id := range ids {
+		role, found := roles[id]
+		switch {
+		case !found:
+			missing = append(missing, id.Hex())
+		case !db.RoleAllows(role, required):
+			forbidden = append(forbidden, id.Hex())
+		default:
+			allowed = append(allowed, id)
+		}
+	}
+	return allowed, missing, forbidden, nil
+}
+
+func sharedItem(c *gin.Context, item *db.TodoItemDb, err error) (*shared, error) {
+	// items in the trash are only reachable through the trash endpoints
+	if err != nil || item == nil || item.DeletedAt != nil {
+		return nil, err
+	}
+	return &shared{owner: item.Owner, shares: item.Shares, role: db.ItemRole(c, item)}, nil
+}
+
+func sharedList(c *gin.Context, list *db.TodoListDb, err error) (*shared, error) {
+	if err != nil || list == nil {
+		return nil, err
+	}
+	return &shared{owner: list.Owner, shares: list.Shares, role: db.ListRole(c, list)}, nil
+}
+
+func (con *ShareController) items() *shareStore {
+	return &shareStore{
+		find: func(c *gin.Context, id primitive.ObjectID) (*shared, error) {
+			item, err := con.TodoItemDbHandler.FindOneById(c, id)
+			return sharedItem(c, item, err)
+		},
+		set: func(c *gin.Context, id primitive.ObjectID, share db.Share) (*shared, error) {
+			item, err := con.TodoItemDbHandler.SetShare(c, id, share)
+			return sharedItem(c, item, err)
+		},
+		remove: func(c *gin.Context, id primitive.ObjectID, user primitive.ObjectID) (*shared, error) {
+			item, err := con.TodoItemDbHandler.RemoveShare(c, id, user)
+			return sharedItem(c, item, err)
+		},
+	}
+}
+
+func (con *ShareController) lists() *shareStore {
+	return &shareStore{
+		find: func(c *gin.Context, id primitive.ObjectID) (*shared, error) {
+			list, err := con.TodoListDbHandler.FindOneById(c, id)
+			return sharedList(c, list, err)
+		},
+		set: func(c *gin.Context, id primitive.ObjectID, share db.Share) (*shared, error) {
+			list, err := con.TodoListDbHandler.SetShare(c, id, share)
+			return sharedList(c, list, err)
+		},
+		remove: func(c *gin.Context, id primitive.ObjectID, user primitive.ObjectID) (*shared, error) {
+			list, err := con.TodoListDbHandler.RemoveShare(c, id, user)
+			return sharedList(c, list, err)
+		},
+	}
+}
+
+func (con *ShareController) ItemShares(c *gin.Context)     { con.findShares(c, con.items()) }
+func (con *ShareController) ShareItem(c *gin.Context)      { con.share(c, con.items()) }
+func (con *ShareController) ChangeItemRole(c *gin.Context) { con.changeRole(c, con.items()) }
+func (con *ShareController) UnshareItem(c *gin.Context)    { con.unshare(c, con.items()) }
+
+func (con *ShareController) ListShares(c *gin.Context)     { con.findShares(c, con.lists()) }
+func (con *ShareController) ShareList(c *gin.Context)      { con.share(c, con.lists()) }
+func (con *ShareController) ChangeListRole(c *gin.Context) { con.changeRole(c, con.lists()) }
+func (con *ShareController) UnshareList(c *gin.Context)    { con.unshare(c, con.lists()) }
+
+// findShared reads the item or list of the path, it aborts the request when it isn't there
+func (con *ShareController) findShared(c *gin.Context, store *shareStore) (primitive.ObjectID, *shared) {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return id, nil
+	}
+
+	found, err := store.find(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return id, nil
+	}
+
+	if found == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return id, nil
+	}
+
+	return id, found
+}
+
+// findShares responds with the owner and the shares
+func (con *ShareController) findShares(c *gin.Context, store *shareStore) {
+	_, found := con.findShared(c, store)
+	if found == nil {
+		return
+	}
+
+	respondWithShares(c, http.StatusOK, found)
+}
+
+// share invites a user who has no share yet, changing the role of a user is done by changeRole
+func (con *ShareController) share(c *gin.Context, store *shareStore) {
+	id, found := con.findShared(c, store)
+	if found == nil || !allowed(c, found.role, db.RoleOwner) {
+		return
+	}
+
+	body := &NewShareBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	user, _ := primitive.ObjectIDFromHex(body.UserId)
+	if user == found.owner {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("the owner can't be invited"))
+		return
+	}
+
+	if shareOf(found, user) != nil {
+		c.AbortWithError(http.StatusConflict, fmt.Errorf("the user was already invited, change their role instead"))
+		return
+	}
+
+	invited, err := con.UserDbHandler.FindOneById(c, user)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if invited == nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("the user doesn't exist"))
+		return
+	}
+
+	con.setShare(c, store, id, db.Share{User: user, Role: body.Role}, http.StatusCreated)
+}
+
+// changeRole changes the role of the user of the path, who has to be invited already
+func (con *ShareController) changeRole(c *gin.Context, store *shareStore) {
+	id, found := con.findShared(c, store)
+	if found == nil || !allowed(c, found.role, db.RoleOwner) {
+		return
+	}
+
+	user, ok := con.invitedUser(c, found)
+	if !ok {
+		return
+	}
+
+	body := &RoleBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	con.setShare(c, store, id, db.Share{User: user, Role: body.Role}, http.StatusOK)
+}
+
+// unshare revokes the access of the user of the path, users can always revoke their own access
+func (con *ShareController) unshare(c *gin.Context, store *shareStore) {
+	id, found := con.findShared(c, store)
+	if found == nil {
+		return
+	}
+
+	user, ok := con.invitedUser(c, found)
+	if !ok {
+		return
+	}
+
+	if caller, _ := db.OwnerFrom(c); caller != user && !allowed(c, found.role, db.RoleOwner) {
+		return
+	}
+
+	revoked, err := store.remove(c, id, user)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if revoked == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// invitedUser reads the user of the path, it aborts the request when they have no share
+func (con *ShareController) invitedUser(c *gin.Context, found *shared) (primitive.ObjectID, bool) {
+	user, err := primitive.ObjectIDFromHex(c.GetString("userId"))
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode user id"))
+		return user, false
+	}
+
+	if shareOf(found, user) == nil {
+		c.AbortWithError(http.StatusNotFound, fmt.Errorf("the user has no share"))
+		return user, false
+	}
+
+	return user, true
+}
+
+func (con *ShareController) setShare(c *gin.Context, store *shareStore, id primitive.ObjectID, share db.Share, status int) {
+	updated, err := store.set(c, id, share)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if updated == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	respondWithShares(c, status, updated)
+}
+
+func shareOf(found *shared, user primitive.ObjectID) *db.Share {
+	for i := range found.shares {
+		if found.shares[i].User == user {
+			return &found.shares[i]
+		}
+	}
+	return nil
+}
+
+func respondWithShares(c *gin.Context, status int, found *shared) {
+	shares := found.shares
+	if shares == nil {
+		shares = []db.Share{}
+	}
+
+	c.JSON(status, &SharesBody{Owner: found.owner, Shares: shares})
+}
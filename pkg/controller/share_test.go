@@ -0,0 +1,201 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"todo-list-service/pkg/controller"
+	"todo-list-service/pkg/db"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+var asOther = map[string]string{middleware.UserHeader: otherUser}
+
+func share(t *testing.T, engine *gin.Engine, path string, role string) {
+	w := doRequest(engine, http.MethodPost, path+"/shares", gin.H{"userId": otherUser, "role": role})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST %s/shares status = %d, want %d: %s", path, w.Code, http.StatusCreated, w.Body)
+	}
+}
+
+func TestShareController_Items(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	id := createItem(t, engine, gin.H{"title": "Shared", "dueDate": "2030-01-01T00:00:00Z"})
+	createItem(t, engine, gin.H{"title": "Private", "dueDate": "2030-01-01T00:00:00Z"})
+	share(t, engine, "/todo/"+id, db.RoleViewer)
+
+	t.Run("Lists the shares", func(t *testing.T) {
+		var shares controller.SharesBody
+		w := doRequestWithHeaders(engine, http.MethodGet, "/todo/"+id+"/shares", nil, asOther)
+		json.Unmarshal(w.Body.Bytes(), &shares)
+		if w.Code != http.StatusOK || shares.Owner.Hex() != testUser || len(shares.Shares) != 1 || shares.Shares[0].Role != db.RoleViewer {
+			t.Errorf("GET /todo/:id/shares = %d %s, want the viewer share", w.Code, w.Body)
+		}
+	})
+
+	t.Run("Viewers see the item but can't change it", func(t *testing.T) {
+		var page controller.TodoItemPageBody
+		json.Unmarshal(doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, asOther).Body.Bytes(), &page)
+		if len(page.Items) != 1 || page.Items[0].Title != "Shared" {
+			t.Errorf("GET /todo of a viewer = %+v, want only the shared item", page.Items)
+		}
+
+		requests := []struct {
+			method, path string
+			body         any
+		}{
+			{http.MethodPut, "/todo/" + id, gin.H{"title": "Changed", "dueDate": "2030-01-01T00:00:00Z"}},
+			{http.MethodPost, "/todo/" + id + "/labels", gin.H{"labels": []string{"work"}}},
+			{http.MethodDelete, "/todo/" + id, nil},
+			{http.MethodPost, "/todo/" + id + "/shares", gin.H{"userId": testUser, "role": db.RoleViewer}},
+		}
+		for _, r := range requests {
+			if w := doRequestWithHeaders(engine, r.method, r.path, r.body, asOther); w.Code != http.StatusForbidden {
+				t.Errorf("%s %s of a viewer status = %d, want %d", r.method, r.path, w.Code, http.StatusForbidden)
+			}
+		}
+	})
+
+	t.Run("Editors can change the item but not delete it", func(t *testing.T) {
+		if w := doRequest(engine, http.MethodPut, "/todo/"+id+"/shares/"+otherUser, gin.H{"role": db.RoleEditor}); w.Code != http.StatusOK {
+			t.Fatalf("PUT /todo/:id/shares/:userId status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+
+		w := doRequestWithHeaders(engine, http.MethodPost, "/todo/"+id+"/labels", gin.H{"labels": []string{"work"}}, asOther)
+		if w.Code != http.StatusOK {
+			t.Errorf("POST /todo/:id/labels of an editor status = %d, want %d", w.Code, http.StatusOK)
+		}
+		if w := doRequestWithHeaders(engine, http.MethodDelete, "/todo/"+id, nil, asOther); w.Code != http.StatusForbidden {
+			t.Errorf("DELETE /todo/:id of an editor status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("Editors can't move the item into a list of their own", func(t *testing.T) {
+		w := doRequestWithHeaders(engine, http.MethodPost, "/lists", gin.H{"name": "Theirs"}, asOther)
+		var list struct {
+			Id string `json:"id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &list)
+
+		requests := []struct {
+			method, path string
+			body         any
+			headers      map[string]string
+		}{
+			{http.MethodPut, "/todo/" + id, gin.H{"title": "Shared", "dueDate": "2030-01-01T00:00:00Z", "listId": list.Id}, nil},
+			{http.MethodPatch, "/todo/" + id, gin.H{"listId": list.Id}, map[string]string{"Content-Type": controller.MergePatchContentType}},
+		}
+		for _, r := range requests {
+			headers := map[string]string{middleware.UserHeader: otherUser}
+			for key, value := range r.headers {
+				headers[key] = value
+			}
+			if w := doRequestWithHeaders(engine, r.method, r.path, r.body, headers); w.Code != http.StatusForbidden {
+				t.Errorf("%s %s into a list of an editor status = %d, want %d", r.method, r.path, w.Code, http.StatusForbidden)
+			}
+		}
+
+		var moved struct {
+			Forbidden []string `json:"forbidden"`
+		}
+		w = doRequestWithHeaders(engine, http.MethodPost, "/lists/"+list.Id+"/todo", gin.H{"ids": []string{id}}, asOther)
+		json.Unmarshal(w.Body.Bytes(), &moved)
+		if w.Code != http.StatusOK || !slices.Equal(moved.Forbidden, []string{id}) {
+			t.Errorf("POST /lists/:id/todo of an editor = %d %s, want the item to be forbidden", w.Code, w.Body)
+		}
+
+		if w := doRequestWithHeaders(engine, http.MethodDelete, "/todo/"+id, nil, asOther); w.Code != http.StatusForbidden {
+			t.Errorf("DELETE /todo/:id of an editor after moving status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("Rejects invalid invites", func(t *testing.T) {
+		for _, body := range []gin.H{
+			{"userId": testUser, "role": db.RoleViewer},
+			{"userId": "65b0000000000000000000ff", "role": db.RoleViewer},
+			{"userId": otherUser, "role": "admin"},
+		} {
+			if w := doRequest(engine, http.MethodPost, "/todo/"+id+"/shares", body); w.Code != http.StatusBadRequest {
+				t.Errorf("POST /todo/:id/shares %v status = %d, want %d", body, w.Code, http.StatusBadRequest)
+			}
+		}
+
+		if w := doRequest(engine, http.MethodPost, "/todo/"+id+"/shares", gin.H{"userId": otherUser, "role": db.RoleViewer}); w.Code != http.StatusConflict {
+			t.Errorf("POST /todo/:id/shares of an invited user status = %d, want %d", w.Code, http.StatusConflict)
+		}
+	})
+
+	t.Run("Revokes access", func(t *testing.T) {
+		if w := doRequest(engine, http.MethodDelete, "/todo/"+id+"/shares/"+otherUser, nil); w.Code != http.StatusNoContent {
+			t.Fatalf("DELETE /todo/:id/shares/:userId status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+
+		if w := doRequestWithHeaders(engine, http.MethodGet, "/todo/"+id, nil, asOther); w.Code != http.StatusNotFound {
+			t.Errorf("GET /todo/:id after revoking status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+		if w := doRequest(engine, http.MethodDelete, "/todo/"+id+"/shares/"+otherUser, nil); w.Code != http.StatusNotFound {
+			t.Errorf("DELETE /todo/:id/shares/:userId again status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestShareController_Lists(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	list := createList(t, engine, "Team")
+	createItem(t, engine, gin.H{"title": "Mine", "dueDate": "2030-01-01T00:00:00Z", "listId": list})
+	share(t, engine, "/lists/"+list, db.RoleEditor)
+
+	t.Run("Editors of a list can work with its items", func(t *testing.T) {
+		w := doRequestWithHeaders(engine, http.MethodPost, "/todo", gin.H{"title": "Theirs", "dueDate": "2030-01-01T00:00:00Z", "listId": list}, asOther)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST /todo into a shared list status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+		}
+
+		var page controller.TodoItemPageBody
+		json.Unmarshal(doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, asOther).Body.Bytes(), &page)
+		if len(page.Items) != 2 {
+			t.Errorf("GET /todo of an editor = %+v, want both items of the list", page.Items)
+		}
+
+		if titles := itemTitles(t, engine, "/lists/"+list+"/todo"); !slices.Contains(titles, "Theirs") {
+			t.Errorf("GET /lists/:id/todo of the owner = %v, want the item of the editor", titles)
+		}
+	})
+
+	t.Run("Editors can't archive or delete the list", func(t *testing.T) {
+		if w := doRequestWithHeaders(engine, http.MethodPost, "/lists/"+list+"/archive", nil, asOther); w.Code != http.StatusForbidden {
+			t.Errorf("POST /lists/:id/archive of an editor status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+		if w := doRequestWithHeaders(engine, http.MethodDelete, "/lists/"+list, nil, asOther); w.Code != http.StatusForbidden {
+			t.Errorf("DELETE /lists/:id of an editor status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("Viewers can't add items to the list", func(t *testing.T) {
+		doRequest(engine, http.MethodPut, "/lists/"+list+"/shares/"+otherUser, gin.H{"role": db.RoleViewer})
+
+		w := doRequestWithHeaders(engine, http.MethodPost, "/todo", gin.H{"title": "Denied", "dueDate": "2030-01-01T00:00:00Z", "listId": list}, asOther)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("POST /todo into a list of a viewer status = %d, want %d", w.Code, http.StatusForbidden)
+		}
+	})
+
+	t.Run("Users can leave a shared list", func(t *testing.T) {
+		if w := doRequestWithHeaders(engine, http.MethodDelete, "/lists/"+list+"/shares/"+otherUser, nil, asOther); w.Code != http.StatusNoContent {
+			t.Fatalf("DELETE /lists/:id/shares/:userId of the user themselves status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+
+		var lists controller.TodoListPageBody
+		json.Unmarshal(doRequestWithHeaders(engine, http.MethodGet, "/lists", nil, asOther).Body.Bytes(), &lists)
+		if len(lists.Lists) != 0 {
+			t.Errorf("GET /lists after leaving = %+v, want no lists", lists.Lists)
+		}
+	})
+}
@@ -0,0 +1,254 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// what happens to the children of an item that gets deleted
+const (
+	// the whole subtree moves to the trash, and is restored along with the item
+	SubtaskDeleteTrash = "trash"
+	// the children become top-level items
+	SubtaskDeleteOrphan = "orphan"
+	// items with children can't be deleted
+	SubtaskDeleteRestrict = "restrict"
+)
+
+// errInvalidParent is returned when the parent of an item doesn't exist or would create a cycle
+var errInvalidParent = errors.New("invalid parent")
+
+// TodoItemNode is an item of a subtree along with its children
+type TodoItemNode struct {
+	TodoItemBody
+	Subtasks []*TodoItemNode `json:"subtasks"`
+}
+
+// TodoItemSubtreeBody is the response of the subtree endpoint, Truncated is set when
+// the subtree has more than MaxReturnArraySize descendants
+type TodoItemSubtreeBody struct {
+	Root      *TodoItemNode `json:"root"`
+	Truncated bool          `json:"truncated"`
+}
+
+// parentObjectId converts the parentId of a body, which was validated to be an object id or empty
+func parentObjectId(hex string) *primitive.ObjectID {
+	if hex == "" {
+		return nil
+	}
+
+	id, _ := primitive.ObjectIDFromHex(hex)
+	return &id
+}
+
+// checkParent makes sure the parent exists and isn't the item itself or one of its descendants,
+// the id of an item that is being created is the nil object id
+func (con *TodoItemController) checkParent(c *gin.Context, id primitive.ObjectID, parentId *primitive.ObjectID) error {
+	visited := map[primitive.ObjectID]bool{}
+	for current := parentId; current != nil && !visited[*current]; {
+		if *current == id {
+			return fmt.Errorf("%w: an item can't be a subtask of itself or of its subtasks", errInvalidParent)
+		}
+		visited[*current] = true
+
+		item, err := con.TodoItemDbHandler.FindOneById(c, *current)
+		if err != nil {
+			return err
+		}
+
+		if item == nil || item.DeletedAt != nil {
+			if current == parentId {
+				return fmt.Errorf("%w: the parent item doesn't exist", errInvalidParent)
+			}
+			return nil
+		}
+		current = item.ParentId
+	}
+
+	return nil
+}
+
+// addProgress sets the completion rollup of the items that have children
+func (con *TodoItemController) addProgress(c *gin.Context, items []*TodoItemBody) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	ids := make([]primitive.ObjectID, len(items))
+	for i, item := range items {
+		ids[i] = item.Id
+	}
+
+	counts, err := con.TodoItemDbHandler.CountChildren(c, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if count, ok := counts[item.Id]; ok {
+			item.Progress = &count
+		}
+	}
+	return nil
+}
+
+// findItem reads the item of the path for the subtask and dependency endpoints, it aborts the request when it isn't there
+func (con *TodoItemController) findItem(c *gin.Context) *db.TodoItemDb {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return nil
+	}
+
+	item, err := con.TodoItemDbHandler.FindOneById(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil
+	}
+
+	if item == nil || item.DeletedAt != nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil
+	}
+
+	return item
+}
+
+// Children lists the direct children of the item, it accepts the same query params as FindAll
+func (con *TodoItemController) Children(c *gin.Context) {
+	parent := con.findItem(c)
+	if parent == nil {
+		return
+	}
+
+	filter, err := todoItemFilter(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	filter.ParentIds = []primitive.ObjectID{parent.Id}
+
+	con.findPage(c, filter)
+}
+
+// Subtree responds with the item and all of its descendants nested as subtasks, up to MaxReturnArraySize descendants
+func (con *TodoItemController) Subtree(c *gin.Context) {
+	parent := con.findItem(c)
+	if parent == nil {
+		return
+	}
+
+	body := &TodoItemSubtreeBody{Root: &TodoItemNode{TodoItemBody: TodoItemBody{TodoItemDb: *parent}, Subtasks: []*TodoItemNode{}}}
+	nodes := map[primitive.ObjectID]*TodoItemNode{parent.Id: body.Root}
+	all := []*TodoItemBody{&body.Root.TodoItemBody}
+
+	level := []primitive.ObjectID{parent.Id}
+	for len(level) > 0 && !body.Truncated {
+		remaining := con.MaxReturnArraySize - len(all) + 1
+		page, err := con.TodoItemDbHandler.FindAll(c, db.TodoItemFilter{ParentIds: level}, db.PageOptions{Limit: remaining})
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		body.Truncated = page.Truncated
+
+		level = nil
+		for _, item := range page.Items {
+			if nodes[item.Id] != nil {
+				continue
+			}
+
+			node := &TodoItemNode{TodoItemBody: TodoItemBody{TodoItemDb: item}, Subtasks: []*TodoItemNode{}}
+			nodes[item.Id] = node
+			nodes[*item.ParentId].Subtasks = append(nodes[*item.ParentId].Subtasks, node)
+			all = append(all, &node.TodoItemBody)
+			level = append(level, item.Id)
+		}
+	}
+
+	if err := con.addProgress(c, all); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, body)
+}
+
+// hasChildren tells whether the item has children outside of the trash
+func (con *TodoItemController) hasChildren(c *gin.Context, id primitive.ObjectID) (bool, error) {
+	counts, err := con.TodoItemDbHandler.CountChildren(c, []primitive.ObjectID{id})
+	if err != nil {
+		return false, err
+	}
+
+	return counts[id].Total > 0, nil
+}
+
+// cascadeDelete applies SubtaskDelete to the children of an item that was moved to the trash
+func (con *TodoItemController) cascadeDelete(c *gin.Context, id primitive.ObjectID) error {
+	switch con.SubtaskDelete {
+	case SubtaskDeleteTrash:
+		level := []primitive.ObjectID{id}
+		for len(level) > 0 {
+			page, err := con.TodoItemDbHandler.FindAll(c, db.TodoItemFilter{ParentIds: level}, db.PageOptions{})
+			if err != nil {
+				return err
+			}
+
+			level = nil
+			for _, child := range page.Items {
+				if _, err := con.TodoItemDbHandler.TrashOneById(c, child.Id, db.AnyVersion); err != nil {
+					return err
+				}
+				level = append(level, child.Id)
+			}
+		}
+
+	case SubtaskDeleteOrphan:
+		page, err := con.TodoItemDbHandler.FindAll(c, db.TodoItemFilter{ParentIds: []primitive.ObjectID{id}}, db.PageOptions{})
+		if err != nil {
+			return err
+		}
+
+		noParent := primitive.NilObjectID
+		for _, child := range page.Items {
+			if _, err := con.TodoItemDbHandler.PatchOneById(c, child.Id, &db.TodoItemUpdate{ParentId: &noParent}, db.AnyVersion); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// restoreSubtree restores the descendants that were moved to the trash along with the item, which happened at trashedAt or later
+func (con *TodoItemController) restoreSubtree(c *gin.Context, id primitive.ObjectID, trashedAt time.Time) error {
+	level := []primitive.ObjectID{id}
+	for len(level) > 0 {
+		page, err := con.TodoItemDbHandler.FindAll(c, db.TodoItemFilter{ParentIds: level, Trashed: true}, db.PageOptions{})
+		if err != nil {
+			return err
+		}
+
+		level = nil
+		for _, child := range page.Items {
+			if child.DeletedAt.Before(trashedAt) {
+				continue
+			}
+
+			if _, err := con.TodoItemDbHandler.UntrashOneById(c, child.Id, db.AnyVersion); err != nil {
+				return err
+			}
+			level = append(level, child.Id)
+		}
+	}
+
+	return nil
+}
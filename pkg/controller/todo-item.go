@@ -1,8 +1,10 @@
 package controller
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"reflect"
 	"time"
 	"todo-list-service/pkg/db"
 
@@ -12,15 +14,42 @@ import (
 
 type TodoItemController struct {
 	TodoItemDbHandler  db.TodoItemDbHandlerInterface
+	TodoItemHistory    db.TodoItemHistoryInterface
+	TodoListDbHandler  db.TodoListDbHandlerInterface
 	MaxReturnArraySize int
+	// SubtaskDelete is one of the SubtaskDelete constants and decides what happens to the children of deleted items
+	SubtaskDelete string
 }
 
 type NewTodoItemBody struct {
-	Title       string    `json:"title" binding:"required"`
+	Title string `json:"title" binding:"required_without=LegacyTitle"`
+	// LegacyTitle accepts the title under the name it had before, Title wins when both are sent
+	LegacyTitle string    `json:"string,omitempty"`
 	DueDate     time.Time `json:"dueDate" binding:"required"`
 	Labels      []string  `json:"labels,omitempty"`
 	Description string    `json:"description,omitempty" field:"''"`
 	Completed   bool      `json:"completed,omitempty" field:"false"`
+	Recurrence  string    `json:"recurrence,omitempty" field:"''"`
+	ParentId    string    `json:"parentId,omitempty" binding:"omitempty,mongodb" field:"''"`
+	ListId      string    `json:"listId,omitempty" binding:"omitempty,mongodb" field:"''"`
+	BlockedBy   []string  `json:"blockedBy,omitempty" binding:"omitempty,dive,mongodb"`
+}
+
+// resolveTitle takes the title from LegacyTitle, when only that one differs from the original title
+func (b *NewTodoItemBody) resolveTitle(original string) {
+	if b.LegacyTitle != original && b.Title == original {
+		b.Title = b.LegacyTitle
+	}
+	b.LegacyTitle = ""
+}
+
+type LabelsBody struct {
+	Labels []string `json:"labels" binding:"required,min=1,dive,required"`
+}
+
+type BulkLabelsBody struct {
+	Ids    []string `json:"ids" binding:"required,min=1,dive,required"`
+	Labels []string `json:"labels" binding:"required,min=1,dive,required"`
 }
 
 func (con *TodoItemController) FindOneById(c *gin.Context) {
@@ -37,47 +66,115 @@ func (con *TodoItemController) FindOneById(c *gin.Context) {
 		return
 	}
 
-	if item == nil {
+	// items in the trash are only reachable through the trash endpoints
+	if item == nil || item.DeletedAt != nil {
 		c.AbortWithStatus(http.StatusNotFound)
 		return
 	}
 
-	c.JSON(http.StatusOK, item)
+	if ifNoneMatch(c, item) {
+		c.Header("ETag", etag(item))
+		c.AbortWithStatus(http.StatusNotModified)
+		return
+	}
+
+	body := &TodoItemBody{TodoItemDb: *item}
+	if err := con.addProgress(c, []*TodoItemBody{body}); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Header("ETag", etag(item))
+	c.JSON(http.StatusOK, body)
 }
 
+// FindAll lists the items, except for the ones of archived lists
 func (con *TodoItemController) FindAll(c *gin.Context) {
-	cur, err := con.TodoItemDbHandler.FindAll(c)
+	filter, err := todoItemFilter(c)
 	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := con.excludeArchived(c, &filter); err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	items, err := con.TodoItemDbHandler.ConsumeCursor(cur, con.MaxReturnArraySize)
+	con.findPage(c, filter)
+}
+
+// FindByLabel accepts the same query params as FindAll, except for the label which comes from the path
+func (con *TodoItemController) FindByLabel(c *gin.Context) {
+	filter, err := todoItemFilter(c)
 	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if len(filter.Labels) > 0 {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("the label is already given by the path"))
+		return
+	}
+	filter.Labels = []string{c.GetString("label")}
+
+	if err := con.excludeArchived(c, &filter); err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, items)
+	con.findPage(c, filter)
 }
 
-func (con *TodoItemController) FindByLabel(c *gin.Context) {
-	labelString := c.GetString("label")
-	cur, err := con.TodoItemDbHandler.FindByLabel(c, labelString)
+func (con *TodoItemController) findPage(c *gin.Context, filter db.TodoItemFilter) {
+	page, err := con.pageOptions(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	window, err := expandWindowParams(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	found, err := con.TodoItemDbHandler.FindAll(c, filter, page)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	items, err := con.TodoItemDbHandler.ConsumeCursor(cur, con.MaxReturnArraySize)
+	body, err := newTodoItemPageBody(found)
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, items)
+	items := make([]*TodoItemBody, len(body.Items))
+	for i := range body.Items {
+		items[i] = &body.Items[i]
+	}
+	if err := con.addProgress(c, items); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if window != nil {
+		for i := range body.Items {
+			body.Items[i].Occurrences, err = con.occurrences(&body.Items[i].TodoItemDb, window)
+			if err != nil {
+				c.AbortWithError(http.StatusInternalServerError, err)
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, body)
 }
 
+// DeleteOneById moves the item to the trash and responds with the trashed item, which takes the owner role.
+// The If-Match header makes the delete conditional on the version of the item
 func (con *TodoItemController) DeleteOneById(c *gin.Context) {
 	idString := c.GetString("id")
 	id, err := primitive.ObjectIDFromHex(idString)
@@ -86,16 +183,52 @@ func (con *TodoItemController) DeleteOneById(c *gin.Context) {
 		return
 	}
 
-	err = con.TodoItemDbHandler.DeleteOneById(c, id)
+	version, err := ifMatchVersion(c)
 	if err != nil {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
+
+	if !con.authorize(c, id, db.RoleOwner) {
+		return
+	}
+
+	if con.SubtaskDelete == SubtaskDeleteRestrict {
+		hasChildren, err := con.hasChildren(c, id)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		if hasChildren {
+			c.AbortWithError(http.StatusConflict, fmt.Errorf("items with subtasks can't be deleted"))
+			return
+		}
+	}
+
+	item, err := con.TodoItemDbHandler.TrashOneById(c, id, version)
+	if errors.Is(err, db.ErrVersionConflict) {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if item == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if err := con.cascadeDelete(c, id); err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	c.AbortWithStatus(http.StatusOK)
+	respondWithItem(c, http.StatusOK, item)
 }
 
-// FIXME: check if the todo item exists
+// UpdateByID replaces the item, the If-Match header makes the update conditional on the version of the item
 func (con *TodoItemController) UpdateByID(c *gin.Context) {
 	idString := c.GetString("id")
 	id, err := primitive.ObjectIDFromHex(idString)
@@ -109,25 +242,192 @@ func (con *TodoItemController) UpdateByID(c *gin.Context) {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
+	todoItem.resolveTitle("")
+
+	current, err := con.TodoItemDbHandler.FindOneById(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if current != nil && !allowed(c, db.ItemRole(c, current), db.RoleEditor) {
+		return
+	}
+	wasCompleted := current != nil && current.Completed
+
+	// items can stay in an archived list, but can't be added to one
+	listId := listObjectId(todoItem.ListId)
+	if current == nil {
+		err = con.checkList(c, listId)
+	} else if !reflect.DeepEqual(current.ListId, listId) {
+		err = con.checkMove(c, current, listId)
+	}
+	if err != nil {
+		c.AbortWithError(listStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	blockedBy := blockerObjectIds(todoItem.BlockedBy)
+	if err := con.checkBlockers(c, id, addedBlockers(current, blockedBy)); err != nil {
+		c.AbortWithError(dependencyStatus(err, http.StatusBadRequest), err)
+		return
+	}
+	if err := con.checkCompletion(c, wasCompleted, todoItem); err != nil {
+		c.AbortWithError(dependencyStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	if err := todoItem.applyRecurrence(); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	parentId := parentObjectId(todoItem.ParentId)
+	if err := con.checkParent(c, id, parentId); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errInvalidParent) {
+			status = http.StatusBadRequest
+		}
+		c.AbortWithError(status, err)
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
 
-	err = con.TodoItemDbHandler.UpdateOneById(c, id, &db.TodoItemDb{
+	item, err := con.TodoItemDbHandler.UpdateOneById(c, id, &db.TodoItemDb{
 		Title:       todoItem.Title,
 		DueDate:     todoItem.DueDate,
 		Labels:      todoItem.Labels,
 		Description: todoItem.Description,
 		Completed:   todoItem.Completed,
-	})
+		Recurrence:  todoItem.Recurrence,
+		ParentId:    parentId,
+		ListId:      listId,
+		BlockedBy:   blockedBy,
+	}, version)
+	if errors.Is(err, db.ErrVersionConflict) {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
 
-	item, err := con.TodoItemDbHandler.FindOneById(c, id)
+	if item == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	respondWithItem(c, http.StatusOK, item)
+}
+
+// AddLabels adds the labels of the body to the item and responds with the updated item
+func (con *TodoItemController) AddLabels(c *gin.Context) {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return
+	}
+
+	body := &LabelsBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if !con.authorize(c, id, db.RoleEditor) {
+		return
+	}
+
+	item, err := con.TodoItemDbHandler.AddLabels(c, id, body.Labels...)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if item == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	respondWithItem(c, http.StatusOK, item)
+}
+
+// AddLabelsToMany adds the labels to every item of the body the user may edit, it responds with the updated items,
+// the ids that don't exist and the ids of the items the user may only view
+func (con *TodoItemController) AddLabelsToMany(c *gin.Context) {
+	body := &BulkLabelsBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if len(body.Ids) > con.MaxReturnArraySize {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("at most %d items can be labelled at once", con.MaxReturnArraySize))
+		return
+	}
+
+	ids := make([]primitive.ObjectID, len(body.Ids))
+	for i, idString := range body.Ids {
+		id, err := primitive.ObjectIDFromHex(idString)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id %q", idString))
+			return
+		}
+		ids[i] = id
+	}
+
+	editable, missing, forbidden, err := con.allowedIds(c, ids, db.RoleEditor)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	err = con.TodoItemDbHandler.AddLabelsToMany(c, editable, body.Labels...)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	page, err := con.TodoItemDbHandler.FindAll(c, db.TodoItemFilter{Ids: editable}, db.PageOptions{})
 	if err != nil {
 		c.AbortWithError(http.StatusInternalServerError, err)
 		return
 	}
-	c.JSON(http.StatusOK, item)
+
+	c.JSON(http.StatusOK, gin.H{"items": page.Items, "missing": missing, "forbidden": forbidden})
+}
+
+// RemoveLabel removes the label of the path from the item and responds with the updated item
+func (con *TodoItemController) RemoveLabel(c *gin.Context) {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return
+	}
+
+	if !con.authorize(c, id, db.RoleEditor) {
+		return
+	}
+
+	item, err := con.TodoItemDbHandler.RemoveLabel(c, id, c.GetString("label"))
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if item == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	respondWithItem(c, http.StatusOK, item)
 }
 
 // Create is a method of TodoItemController that handles the creation of a new TodoItem.
@@ -142,6 +442,38 @@ func (con *TodoItemController) Create(c *gin.Context) {
 		c.AbortWithError(http.StatusBadRequest, err)
 		return
 	}
+	todoItem.resolveTitle("")
+
+	listId := listObjectId(todoItem.ListId)
+	if err := con.checkList(c, listId); err != nil {
+		c.AbortWithError(listStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	blockedBy := blockerObjectIds(todoItem.BlockedBy)
+	if err := con.checkBlockers(c, primitive.NilObjectID, blockedBy); err != nil {
+		c.AbortWithError(dependencyStatus(err, http.StatusBadRequest), err)
+		return
+	}
+	if err := con.checkCompletion(c, false, todoItem); err != nil {
+		c.AbortWithError(dependencyStatus(err, http.StatusBadRequest), err)
+		return
+	}
+
+	if err := todoItem.applyRecurrence(); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	parentId := parentObjectId(todoItem.ParentId)
+	if err := con.checkParent(c, primitive.NilObjectID, parentId); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errInvalidParent) {
+			status = http.StatusBadRequest
+		}
+		c.AbortWithError(status, err)
+		return
+	}
 
 	id, err := con.TodoItemDbHandler.InsertOne(c, &db.TodoItemDb{
 		Title:       todoItem.Title,
@@ -149,6 +481,10 @@ func (con *TodoItemController) Create(c *gin.Context) {
 		Labels:      todoItem.Labels,
 		Description: todoItem.Description,
 		Completed:   todoItem.Completed,
+		Recurrence:  todoItem.Recurrence,
+		ParentId:    parentId,
+		ListId:      listId,
+		BlockedBy:   blockedBy,
 	})
 
 	if err != nil {
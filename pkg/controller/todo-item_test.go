@@ -0,0 +1,1004 @@
+package controller_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"todo-list-service/pkg/controller"
+	"todo-list-service/pkg/db"
+	"todo-list-service/pkg/jwt"
+	"todo-list-service/pkg/middleware"
+	"todo-list-service/pkg/router"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the users registered in every engine, requests are made as testUser unless they set the user header themselves
+const (
+	testUser  = "65b000000000000000000001"
+	otherUser = "65b000000000000000000002"
+)
+
+func createEngine() *gin.Engine {
+	return createEngineWithSubtaskDelete(controller.SubtaskDeleteTrash)
+}
+
+func createEngineWithSubtaskDelete(subtaskDelete string) *gin.Engine {
+	return createEngineWith(subtaskDelete, nil)
+}
+
+// createEngineWith identifies users by the user header, or by bearer tokens when there is a verifier
+func createEngineWith(subtaskDelete string, verifier *jwt.Verifier) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(middleware.RequestId())
+	engine.Use(middleware.ErrorHandler())
+	engine.Use(middleware.Actor())
+
+	userHandler := &db.UserMemoryDbHandler{}
+	userController := &controller.UserController{UserDbHandler: userHandler}
+	apiKeyHandler := &db.ApiKeyMemoryDbHandler{}
+	apiKeys := middleware.ApiKey(apiKeyHandler, userHandler)
+	var users *gin.RouterGroup
+	if verifier != nil {
+		users = engine.Group("/", apiKeys, middleware.JWT(verifier), middleware.TokenUser(userHandler))
+	} else {
+		users = engine.Group("/", apiKeys, middleware.User(userHandler))
+		for _, hex := range []string{testUser, otherUser} {
+			id, _ := primitive.ObjectIDFromHex(hex)
+			userHandler.InsertOne(context.Background(), &db.UserDb{Id: id, Name: hex})
+		}
+		router.AttachRegistrationRoutes(engine, userController)
+	}
+	lists := &db.TodoListMemoryDbHandler{}
+	users.Use(middleware.ListRoles(lists))
+	router.AttachUserRoutes(users, userController)
+	router.AttachApiKeyRoutes(users, &controller.ApiKeyController{ApiKeyDbHandler: apiKeyHandler})
+
+	history := &db.TodoItemHistory{
+		TodoItemDbHandlerInterface: &db.TodoItemMemoryDbHandler{},
+		Revisions:                  &db.RevisionMemoryDbHandler{},
+	}
+	items := &controller.TodoItemController{
+		TodoItemDbHandler:  history,
+		TodoItemHistory:    history,
+		TodoListDbHandler:  lists,
+		MaxReturnArraySize: 100,
+		SubtaskDelete:      subtaskDelete,
+	}
+	router.AttachTodoItemRoutes(users, items)
+	router.AttachTodoListRoutes(users, &controller.TodoListController{
+		TodoListDbHandler:  lists,
+		Items:              items,
+		MaxReturnArraySize: 100,
+	})
+	router.AttachShareRoutes(users, &controller.ShareController{
+		TodoItemDbHandler: history,
+		TodoListDbHandler: lists,
+		UserDbHandler:     userHandler,
+	})
+	return engine
+}
+
+func doRequest(engine *gin.Engine, method, path string, body any) *httptest.ResponseRecorder {
+	return doRequestWithHeaders(engine, method, path, body, nil)
+}
+
+func doRequestWithHeaders(engine *gin.Engine, method, path string, body any, headers map[string]string) *httptest.ResponseRecorder {
+	var reader *bytes.Reader
+	if body != nil {
+		b, _ := json.Marshal(body)
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(middleware.UserHeader, testUser)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func createItem(t *testing.T, engine *gin.Engine, body gin.H) string {
+	w := doRequest(engine, http.MethodPost, "/todo", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /todo status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	var res struct {
+		Id string `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res.Id
+}
+
+func TestTodoItemController_Create(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Successfully created an item", func(t *testing.T) {
+		engine := createEngine()
+		id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z"})
+
+		w := doRequest(engine, http.MethodGet, "/todo/"+id, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET /todo/:id status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("Accepts and returns the legacy name of the title", func(t *testing.T) {
+		engine := createEngine()
+		id := createItem(t, engine, gin.H{"string": "Test_Title", "dueDate": "2030-01-01T00:00:00Z"})
+
+		w := doRequest(engine, http.MethodGet, "/todo/"+id, nil)
+		var item map[string]any
+		json.Unmarshal(w.Body.Bytes(), &item)
+		if item["title"] != "Test_Title" || item["string"] != "Test_Title" {
+			t.Errorf("GET /todo/:id = %v, want the title under both names", item)
+		}
+	})
+
+	t.Run("Rejects an item without title", func(t *testing.T) {
+		engine := createEngine()
+
+		w := doRequest(engine, http.MethodPost, "/todo", gin.H{"dueDate": "2030-01-01T00:00:00Z"})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /todo status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestTodoItemController_FindOneById(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+
+	t.Run("Returns 400 for a malformed id", func(t *testing.T) {
+		w := doRequest(engine, http.MethodGet, "/todo/not-an-id", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET /todo/:id status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Returns 404 for a non-existing item", func(t *testing.T) {
+		w := doRequest(engine, http.MethodGet, "/todo/65a000000000000000000000", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET /todo/:id status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestTodoItemController_FindByLabel(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	createItem(t, engine, gin.H{"title": "Labelled", "dueDate": "2030-01-01T00:00:00Z", "labels": []string{"work"}})
+	createItem(t, engine, gin.H{"title": "Unlabelled", "dueDate": "2030-01-01T00:00:00Z"})
+
+	w := doRequest(engine, http.MethodGet, "/todo/label/work", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /todo/label/:label status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var page controller.TodoItemPageBody
+	json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Items) != 1 || page.Items[0].Title != "Labelled" {
+		t.Errorf("GET /todo/label/:label = %v, want only the labelled item", page.Items)
+	}
+}
+
+func TestTodoItemController_UpdateAndDelete(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z"})
+
+	w := doRequest(engine, http.MethodPut, "/todo/"+id, gin.H{"title": "New_Title", "dueDate": "2030-01-01T00:00:00Z"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT /todo/:id status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var item db.TodoItemDb
+	json.Unmarshal(w.Body.Bytes(), &item)
+	if item.Title != "New_Title" {
+		t.Errorf("PUT /todo/:id title = %q, want %q", item.Title, "New_Title")
+	}
+
+	w = doRequest(engine, http.MethodDelete, "/todo/"+id, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("DELETE /todo/:id status = %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = doRequest(engine, http.MethodGet, "/todo/"+id, nil)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /todo/:id after delete status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestTodoItemController_FindAll(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	for _, title := range []string{"first", "second", "third"} {
+		createItem(t, engine, gin.H{"title": title, "dueDate": "2030-01-01T00:00:00Z"})
+	}
+
+	t.Run("Walks all pages with the next token", func(t *testing.T) {
+		var titles []string
+		path := "/todo?limit=2"
+		for pages := 0; pages < 5; pages++ {
+			w := doRequest(engine, http.MethodGet, path, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s status = %d, want %d", path, w.Code, http.StatusOK)
+			}
+
+			var page controller.TodoItemPageBody
+			json.Unmarshal(w.Body.Bytes(), &page)
+			for _, item := range page.Items {
+				titles = append(titles, item.Title)
+			}
+
+			if !page.Truncated {
+				break
+			}
+			path = "/todo?limit=2&after=" + page.Next
+		}
+
+		if want := []string{"first", "second", "third"}; !reflect.DeepEqual(titles, want) {
+			t.Errorf("GET /todo pages = %v, want %v", titles, want)
+		}
+	})
+
+	t.Run("Filters and sorts", func(t *testing.T) {
+		doRequest(engine, http.MethodPut, "/todo/"+createItem(t, engine, gin.H{"title": "fourth", "dueDate": "2030-01-01T00:00:00Z"}), gin.H{"title": "fourth", "dueDate": "2030-01-01T00:00:00Z", "completed": true})
+
+		w := doRequest(engine, http.MethodGet, "/todo?completed=false&q=IR&sort=-title", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /todo status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+
+		var page controller.TodoItemPageBody
+		json.Unmarshal(w.Body.Bytes(), &page)
+		titles := []string{}
+		for _, item := range page.Items {
+			titles = append(titles, item.Title)
+		}
+		if want := []string{"third", "first"}; !reflect.DeepEqual(titles, want) {
+			t.Errorf("GET /todo = %v, want %v", titles, want)
+		}
+	})
+
+	t.Run("Rejects invalid query params", func(t *testing.T) {
+		for _, path := range []string{
+			"/todo?limit=0", "/todo?limit=abc", "/todo?after=%21%21",
+			"/todo?sort=description", "/todo?completed=maybe", "/todo?dueBefore=tomorrow", "/todo?labelMatch=some", "/todo?color=red",
+		} {
+			w := doRequest(engine, http.MethodGet, path, nil)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("GET %s status = %d, want %d", path, w.Code, http.StatusBadRequest)
+			}
+		}
+	})
+}
+
+func TestTodoItemController_Labels(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z"})
+	otherId := createItem(t, engine, gin.H{"title": "Other_Title", "dueDate": "2030-01-01T00:00:00Z"})
+
+	t.Run("Adds and removes labels", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/todo/"+id+"/labels", gin.H{"labels": []string{"home", "work"}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST /todo/:id/labels status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+
+		var item db.TodoItemDb
+		json.Unmarshal(w.Body.Bytes(), &item)
+		if want := []string{"home", "work"}; !reflect.DeepEqual(item.Labels, want) {
+			t.Errorf("POST /todo/:id/labels labels = %v, want %v", item.Labels, want)
+		}
+
+		w = doRequest(engine, http.MethodDelete, "/todo/"+id+"/labels/home", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("DELETE /todo/:id/labels/:label status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		item = db.TodoItemDb{}
+		json.Unmarshal(w.Body.Bytes(), &item)
+		if want := []string{"work"}; !reflect.DeepEqual(item.Labels, want) {
+			t.Errorf("DELETE /todo/:id/labels/:label labels = %v, want %v", item.Labels, want)
+		}
+	})
+
+	t.Run("Returns 404 for a non-existing item", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/todo/65a000000000000000000000/labels", gin.H{"labels": []string{"home"}})
+		if w.Code != http.StatusNotFound {
+			t.Errorf("POST /todo/:id/labels status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+
+		w = doRequest(engine, http.MethodDelete, "/todo/65a000000000000000000000/labels/home", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("DELETE /todo/:id/labels/:label status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Rejects an empty label list", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/todo/"+id+"/labels", gin.H{"labels": []string{}})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /todo/:id/labels status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Labels many items at once", func(t *testing.T) {
+		missingId := "65a000000000000000000000"
+		w := doRequest(engine, http.MethodPost, "/todo/labels", gin.H{"ids": []string{id, otherId, missingId}, "labels": []string{"bulk"}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST /todo/labels status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+
+		var res struct {
+			Items   []db.TodoItemDb `json:"items"`
+			Missing []string        `json:"missing"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		if len(res.Items) != 2 || !reflect.DeepEqual(res.Missing, []string{missingId}) {
+			t.Errorf("POST /todo/labels = %+v, want 2 items and %s missing", res, missingId)
+		}
+		for _, item := range res.Items {
+			if !slices.Contains(item.Labels, "bulk") {
+				t.Errorf("POST /todo/labels item labels = %v, want bulk", item.Labels)
+			}
+		}
+	})
+}
+
+func doPatch(engine *gin.Engine, path, contentType, patch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(patch))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set(middleware.UserHeader, testUser)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestTodoItemController_PatchByID(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z", "labels": []string{"home"}, "description": "Test_Description"})
+
+	tests := []struct {
+		name        string
+		contentType string
+		patch       string
+		wantStatus  int
+		check       func(db.TodoItemDb) bool
+	}{
+		{"Merge patch completes the item", controller.MergePatchContentType, `{"completed": true}`, http.StatusOK,
+			func(item db.TodoItemDb) bool { return item.Completed && item.Title == "Test_Title" }},
+		{"Merge patch sets completed back to false", controller.MergePatchContentType, `{"completed": false}`, http.StatusOK,
+			func(item db.TodoItemDb) bool { return !item.Completed }},
+		{"Merge patch clears fields", controller.MergePatchContentType, `{"description": null, "labels": null}`, http.StatusOK,
+			func(item db.TodoItemDb) bool { return item.Description == "" && len(item.Labels) == 0 }},
+		{"JSON patch appends a label", controller.JsonPatchContentType, `[{"op": "add", "path": "/labels/-", "value": "work"}]`, http.StatusOK,
+			func(item db.TodoItemDb) bool { return reflect.DeepEqual(item.Labels, []string{"work"}) }},
+		{"JSON patch replaces the title", controller.JsonPatchContentType, `[{"op": "test", "path": "/title", "value": "Test_Title"}, {"op": "replace", "path": "/title", "value": "New_Title"}]`, http.StatusOK,
+			func(item db.TodoItemDb) bool { return item.Title == "New_Title" }},
+		{"Merge patch replaces the title by its legacy name", controller.MergePatchContentType, `{"string": "Legacy_Title"}`, http.StatusOK,
+			func(item db.TodoItemDb) bool { return item.Title == "Legacy_Title" }},
+		{"JSON patch with a failing test", controller.JsonPatchContentType, `[{"op": "test", "path": "/title", "value": "Other"}]`, http.StatusConflict, nil},
+		{"Removing a required field", controller.MergePatchContentType, `{"title": null}`, http.StatusUnprocessableEntity, nil},
+		{"Adding an unknown field", controller.MergePatchContentType, `{"id": "65a000000000000000000000"}`, http.StatusUnprocessableEntity, nil},
+		{"Malformed patch", controller.JsonPatchContentType, `{"op": "add"}`, http.StatusBadRequest, nil},
+		{"Plain JSON", "application/json", `{"completed": true}`, http.StatusUnsupportedMediaType, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := doPatch(engine, "/todo/"+id, tt.contentType, tt.patch)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("PATCH /todo/:id status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+
+			if tt.check != nil {
+				var item db.TodoItemDb
+				json.Unmarshal(w.Body.Bytes(), &item)
+				if !tt.check(item) {
+					t.Errorf("PATCH /todo/:id = %+v", item)
+				}
+			}
+		})
+	}
+
+	t.Run("Returns 404 for a non-existing item", func(t *testing.T) {
+		w := doPatch(engine, "/todo/65a000000000000000000000", controller.MergePatchContentType, `{"completed": true}`)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("PATCH /todo/:id status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestTodoItemController_ETags(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z"})
+	body := gin.H{"title": "New_Title", "dueDate": "2030-01-01T00:00:00Z"}
+
+	w := doRequest(engine, http.MethodGet, "/todo/"+id, nil)
+	tag := w.Header().Get("ETag")
+	if tag == "" {
+		t.Fatalf("GET /todo/:id returned no ETag")
+	}
+
+	t.Run("Returns 304 when the ETag still matches", func(t *testing.T) {
+		w := doRequestWithHeaders(engine, http.MethodGet, "/todo/"+id, nil, map[string]string{"If-None-Match": tag})
+		if w.Code != http.StatusNotModified {
+			t.Errorf("GET /todo/:id status = %d, want %d", w.Code, http.StatusNotModified)
+		}
+	})
+
+	w = doRequestWithHeaders(engine, http.MethodPut, "/todo/"+id, body, map[string]string{"If-Match": tag})
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT /todo/:id status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	newTag := w.Header().Get("ETag")
+	if newTag == tag {
+		t.Fatalf("PUT /todo/:id kept ETag %s", tag)
+	}
+
+	t.Run("Returns 200 once the ETag is outdated", func(t *testing.T) {
+		w := doRequestWithHeaders(engine, http.MethodGet, "/todo/"+id, nil, map[string]string{"If-None-Match": tag})
+		if w.Code != http.StatusOK {
+			t.Errorf("GET /todo/:id status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("Rejects writes with an outdated ETag", func(t *testing.T) {
+		w := doRequestWithHeaders(engine, http.MethodPut, "/todo/"+id, body, map[string]string{"If-Match": tag})
+		if w.Code != http.StatusPreconditionFailed {
+			t.Errorf("PUT /todo/:id status = %d, want %d", w.Code, http.StatusPreconditionFailed)
+		}
+
+		req := httptest.NewRequest(http.MethodPatch, "/todo/"+id, strings.NewReader(`{"completed": true}`))
+		req.Header.Set("Content-Type", controller.MergePatchContentType)
+		req.Header.Set("If-Match", tag)
+		req.Header.Set(middleware.UserHeader, testUser)
+		w = httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != http.StatusPreconditionFailed {
+			t.Errorf("PATCH /todo/:id status = %d, want %d", w.Code, http.StatusPreconditionFailed)
+		}
+
+		w = doRequestWithHeaders(engine, http.MethodDelete, "/todo/"+id, nil, map[string]string{"If-Match": tag})
+		if w.Code != http.StatusPreconditionFailed {
+			t.Errorf("DELETE /todo/:id status = %d, want %d", w.Code, http.StatusPreconditionFailed)
+		}
+	})
+
+	t.Run("Rejects writes with a foreign ETag", func(t *testing.T) {
+		w := doRequestWithHeaders(engine, http.MethodPut, "/todo/"+id, body, map[string]string{"If-Match": `W/"1"`})
+		if w.Code != http.StatusPreconditionFailed {
+			t.Errorf("PUT /todo/:id status = %d, want %d", w.Code, http.StatusPreconditionFailed)
+		}
+	})
+
+	t.Run("Deletes with the current ETag", func(t *testing.T) {
+		w := doRequestWithHeaders(engine, http.MethodDelete, "/todo/"+id, nil, map[string]string{"If-Match": newTag})
+		if w.Code != http.StatusOK {
+			t.Errorf("DELETE /todo/:id status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+}
+
+func TestTodoItemController_History(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z"})
+	doRequest(engine, http.MethodPut, "/todo/"+id, gin.H{"title": "New_Title", "dueDate": "2030-01-01T00:00:00Z"})
+	doRequest(engine, http.MethodDelete, "/todo/"+id, nil)
+	doRequest(engine, http.MethodDelete, "/trash/"+id, nil)
+
+	t.Run("Lists every change", func(t *testing.T) {
+		w := doRequest(engine, http.MethodGet, "/todo/"+id+"/history", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /todo/:id/history status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var revisions []db.TodoItemRevision
+		json.Unmarshal(w.Body.Bytes(), &revisions)
+		actions := []string{}
+		for _, revision := range revisions {
+			actions = append(actions, revision.Action)
+		}
+		if want := []string{"create", "update", "trash", "delete"}; !reflect.DeepEqual(actions, want) {
+			t.Fatalf("GET /todo/:id/history actions = %v, want %v", actions, want)
+		}
+
+		update := revisions[1]
+		if len(update.Changes) != 1 || update.Changes[0].Field != "title" || update.Changes[0].From != "Test_Title" || update.Changes[0].To != "New_Title" {
+			t.Errorf("GET /todo/:id/history changes = %+v, want only the title", update.Changes)
+		}
+		if update.Actor == "" {
+			t.Errorf("GET /todo/:id/history actor is empty")
+		}
+	})
+
+	t.Run("Restores a deleted item", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/todo/"+id+"/restore?revision=0", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST /todo/:id/restore status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+
+		var item db.TodoItemDb
+		json.Unmarshal(w.Body.Bytes(), &item)
+		if item.Title != "Test_Title" || item.Version != 4 {
+			t.Errorf("POST /todo/:id/restore = %+v, want the first title in version 4", item)
+		}
+
+		w = doRequest(engine, http.MethodGet, "/todo/"+id, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET /todo/:id after restore status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("Restores an existing item", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/todo/"+id+"/restore?revision=1", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST /todo/:id/restore status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+
+		var item db.TodoItemDb
+		json.Unmarshal(w.Body.Bytes(), &item)
+		if item.Title != "New_Title" {
+			t.Errorf("POST /todo/:id/restore title = %q, want %q", item.Title, "New_Title")
+		}
+	})
+
+	t.Run("Returns 404 for unknown revisions and items", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/todo/"+id+"/restore?revision=42", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("POST /todo/:id/restore status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+
+		w = doRequest(engine, http.MethodGet, "/todo/65a000000000000000000000/history", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET /todo/:id/history status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestTodoItemController_RestoreChecks(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Refuses to restore a subtask cycle", func(t *testing.T) {
+		engine := createEngine()
+		parent := createItem(t, engine, gin.H{"title": "Parent", "dueDate": "2030-01-01T00:00:00Z"})
+		child := createItem(t, engine, gin.H{"title": "Child", "dueDate": "2030-01-01T00:00:00Z"})
+		doPatch(engine, "/todo/"+parent, controller.MergePatchContentType, `{"parentId": "`+child+`"}`)
+		doPatch(engine, "/todo/"+parent, controller.MergePatchContentType, `{"parentId": null}`)
+		if w := doPatch(engine, "/todo/"+child, controller.MergePatchContentType, `{"parentId": "`+parent+`"}`); w.Code != http.StatusOK {
+			t.Fatalf("PATCH /todo/:id status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+
+		w := doRequest(engine, http.MethodPost, "/todo/"+parent+"/restore?revision=1", nil)
+		if w.Code != http.StatusConflict {
+			t.Errorf("POST /todo/:id/restore status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+		}
+	})
+
+	t.Run("Refuses to restore a dependency cycle", func(t *testing.T) {
+		engine := createEngine()
+		design := createItem(t, engine, gin.H{"title": "Design", "dueDate": "2030-01-01T00:00:00Z"})
+		build := createItem(t, engine, gin.H{"title": "Build", "dueDate": "2030-01-01T00:00:00Z"})
+		doPatch(engine, "/todo/"+design, controller.MergePatchContentType, `{"blockedBy": ["`+build+`"]}`)
+		doPatch(engine, "/todo/"+design, controller.MergePatchContentType, `{"blockedBy": []}`)
+		doPatch(engine, "/todo/"+build, controller.MergePatchContentType, `{"blockedBy": ["`+design+`"]}`)
+
+		w := doRequest(engine, http.MethodPost, "/todo/"+design+"/restore?revision=1", nil)
+		if w.Code != http.StatusConflict {
+			t.Errorf("POST /todo/:id/restore status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+		}
+	})
+
+	t.Run("Refuses to restore into an archived list", func(t *testing.T) {
+		engine := createEngine()
+		list := createList(t, engine, "Work")
+		id := createItem(t, engine, gin.H{"title": "Report", "dueDate": "2030-01-01T00:00:00Z", "listId": list})
+		doPatch(engine, "/todo/"+id, controller.MergePatchContentType, `{"listId": null}`)
+		doRequest(engine, http.MethodPost, "/lists/"+list+"/archive", nil)
+
+		w := doRequest(engine, http.MethodPost, "/todo/"+id+"/restore?revision=0", nil)
+		if w.Code != http.StatusConflict {
+			t.Errorf("POST /todo/:id/restore status = %d, want %d: %s", w.Code, http.StatusConflict, w.Body)
+		}
+
+		var item db.TodoItemDb
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/todo/"+id, nil).Body.Bytes(), &item)
+		if item.ListId != nil {
+			t.Errorf("GET /todo/:id list = %v, want none after the refused restore", item.ListId)
+		}
+	})
+}
+
+func TestTodoItemController_Trash(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z", "labels": []string{"home"}})
+	otherId := createItem(t, engine, gin.H{"title": "Other_Title", "dueDate": "2030-01-01T00:00:00Z", "labels": []string{"home"}})
+
+	listIds := func(path string) []string {
+		w := doRequest(engine, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want %d", path, w.Code, http.StatusOK)
+		}
+
+		var page struct {
+			Items []db.TodoItemDb `json:"items"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &page)
+		ids := []string{}
+		for _, item := range page.Items {
+			ids = append(ids, item.Id.Hex())
+		}
+		return ids
+	}
+
+	t.Run("Moves deleted items to the trash", func(t *testing.T) {
+		w := doRequest(engine, http.MethodDelete, "/todo/"+id, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("DELETE /todo/:id status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var item db.TodoItemDb
+		json.Unmarshal(w.Body.Bytes(), &item)
+		if item.DeletedAt == nil {
+			t.Errorf("DELETE /todo/:id deletedAt is empty")
+		}
+
+		if ids := listIds("/todo"); !reflect.DeepEqual(ids, []string{otherId}) {
+			t.Errorf("GET /todo ids = %v, want %v", ids, []string{otherId})
+		}
+		if ids := listIds("/todo/label/home"); !reflect.DeepEqual(ids, []string{otherId}) {
+			t.Errorf("GET /todo/label/:label ids = %v, want %v", ids, []string{otherId})
+		}
+		if ids := listIds("/trash"); !reflect.DeepEqual(ids, []string{id}) {
+			t.Errorf("GET /trash ids = %v, want %v", ids, []string{id})
+		}
+	})
+
+	t.Run("Hides trashed items from the item endpoints", func(t *testing.T) {
+		for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodDelete} {
+			w := doRequest(engine, method, "/todo/"+id, gin.H{"title": "New_Title", "dueDate": "2030-01-01T00:00:00Z"})
+			if w.Code != http.StatusNotFound {
+				t.Errorf("%s /todo/:id status = %d, want %d", method, w.Code, http.StatusNotFound)
+			}
+		}
+	})
+
+	t.Run("Restores trashed items", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/trash/"+id+"/restore", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST /trash/:id/restore status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		w = doRequest(engine, http.MethodGet, "/todo/"+id, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("GET /todo/:id after restore status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		w = doRequest(engine, http.MethodPost, "/trash/"+id+"/restore", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("POST /trash/:id/restore of a live item status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Deletes only trashed items permanently", func(t *testing.T) {
+		w := doRequest(engine, http.MethodDelete, "/trash/"+id, nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("DELETE /trash/:id of a live item status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+
+		doRequest(engine, http.MethodDelete, "/todo/"+id, nil)
+		w = doRequest(engine, http.MethodDelete, "/trash/"+id, nil)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("DELETE /trash/:id status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+
+		if ids := listIds("/trash"); len(ids) != 0 {
+			t.Errorf("GET /trash ids = %v, want none", ids)
+		}
+	})
+
+	t.Run("Returns 404 when deleting unknown items", func(t *testing.T) {
+		w := doRequest(engine, http.MethodDelete, "/todo/65a000000000000000000000", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("DELETE /todo/:id status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestTodoItemController_Recurrence(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	// 2030-01-01 is a tuesday
+	id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T09:00:00Z", "recurrence": "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=2"})
+
+	complete := func() db.TodoItemDb {
+		w := doPatch(engine, "/todo/"+id, controller.MergePatchContentType, `{"completed": true}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("PATCH /todo/:id status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+
+		var item db.TodoItemDb
+		json.Unmarshal(w.Body.Bytes(), &item)
+		return item
+	}
+
+	t.Run("Rejects invalid rules", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/todo", gin.H{"title": "Test_Title", "dueDate": "2030-01-01T09:00:00Z", "recurrence": "FREQ=HOURLY"})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /todo status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Expands occurrences within a window", func(t *testing.T) {
+		w := doRequest(engine, http.MethodGet, "/todo?expandFrom=2030-01-02T00:00:00Z&expandTo=2030-02-01T00:00:00Z", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /todo status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var page controller.TodoItemPageBody
+		json.Unmarshal(w.Body.Bytes(), &page)
+		want := []time.Time{time.Date(2030, 1, 3, 9, 0, 0, 0, time.UTC)}
+		if len(page.Items) != 1 || !reflect.DeepEqual(page.Items[0].Occurrences, want) {
+			t.Errorf("GET /todo occurrences = %+v, want %v", page.Items, want)
+		}
+
+		w = doRequest(engine, http.MethodGet, "/todo?expandFrom=2030-01-02T00:00:00Z", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET /todo without expandTo status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+		w = doRequest(engine, http.MethodGet, "/todo?expandFrom=0001-01-01T00:00:00Z&expandTo=9999-01-01T00:00:00Z", nil)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET /todo with a window longer than a year status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Completing moves to the next occurrence", func(t *testing.T) {
+		item := complete()
+		if item.Completed || !item.DueDate.Equal(time.Date(2030, 1, 3, 9, 0, 0, 0, time.UTC)) || item.Recurrence != "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=1" {
+			t.Errorf("PATCH /todo/:id = %+v, want the next occurrence", item)
+		}
+	})
+
+	t.Run("Completing the last occurrence completes the item", func(t *testing.T) {
+		item := complete()
+		if !item.Completed || !item.DueDate.Equal(time.Date(2030, 1, 3, 9, 0, 0, 0, time.UTC)) {
+			t.Errorf("PATCH /todo/:id = %+v, want a completed item", item)
+		}
+	})
+}
+
+func TestTodoItemController_Subtasks(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	parent := createItem(t, engine, gin.H{"title": "Parent", "dueDate": "2030-01-01T00:00:00Z"})
+	first := createItem(t, engine, gin.H{"title": "First", "dueDate": "2030-01-01T00:00:00Z", "parentId": parent})
+	second := createItem(t, engine, gin.H{"title": "Second", "dueDate": "2030-01-01T00:00:00Z", "parentId": parent})
+	grandchild := createItem(t, engine, gin.H{"title": "Grandchild", "dueDate": "2030-01-01T00:00:00Z", "parentId": first})
+
+	t.Run("Rejects unknown parents", func(t *testing.T) {
+		for _, parentId := range []string{"65a000000000000000000000", "not-an-id"} {
+			w := doRequest(engine, http.MethodPost, "/todo", gin.H{"title": "Orphan", "dueDate": "2030-01-01T00:00:00Z", "parentId": parentId})
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("POST /todo with parent %s status = %d, want %d", parentId, w.Code, http.StatusBadRequest)
+			}
+		}
+	})
+
+	t.Run("Prevents cycles", func(t *testing.T) {
+		w := doPatch(engine, "/todo/"+parent, controller.MergePatchContentType, `{"parentId": "`+grandchild+`"}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("PATCH /todo/:id status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+		}
+
+		w = doRequest(engine, http.MethodPut, "/todo/"+parent, gin.H{"title": "Parent", "dueDate": "2030-01-01T00:00:00Z", "parentId": parent})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("PUT /todo/:id status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Lists children with their progress", func(t *testing.T) {
+		doPatch(engine, "/todo/"+second, controller.MergePatchContentType, `{"completed": true}`)
+
+		w := doRequest(engine, http.MethodGet, "/todo/"+parent+"/children", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /todo/:id/children status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var page controller.TodoItemPageBody
+		json.Unmarshal(w.Body.Bytes(), &page)
+		if len(page.Items) != 2 || page.Items[0].Title != "First" || page.Items[1].Title != "Second" {
+			t.Fatalf("GET /todo/:id/children = %+v, want the two children", page.Items)
+		}
+		if progress := page.Items[0].Progress; progress == nil || *progress != (db.ChildCount{Done: 0, Total: 1}) {
+			t.Errorf("GET /todo/:id/children progress = %v, want 0 of 1", progress)
+		}
+
+		var item controller.TodoItemBody
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/todo/"+parent, nil).Body.Bytes(), &item)
+		if item.Progress == nil || *item.Progress != (db.ChildCount{Done: 1, Total: 2}) {
+			t.Errorf("GET /todo/:id progress = %v, want 1 of 2", item.Progress)
+		}
+	})
+
+	t.Run("Returns the whole subtree", func(t *testing.T) {
+		w := doRequest(engine, http.MethodGet, "/todo/"+parent+"/subtree", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /todo/:id/subtree status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var body controller.TodoItemSubtreeBody
+		json.Unmarshal(w.Body.Bytes(), &body)
+		root := body.Root
+		if root == nil || len(root.Subtasks) != 2 || len(root.Subtasks[0].Subtasks) != 1 || root.Subtasks[0].Subtasks[0].Title != "Grandchild" || body.Truncated {
+			t.Errorf("GET /todo/:id/subtree = %s, want the parent with two children and a grandchild", w.Body)
+		}
+	})
+
+	t.Run("Trashes and restores the subtree with the parent", func(t *testing.T) {
+		doRequest(engine, http.MethodDelete, "/todo/"+parent, nil)
+		for _, id := range []string{first, second, grandchild} {
+			if w := doRequest(engine, http.MethodGet, "/todo/"+id, nil); w.Code != http.StatusNotFound {
+				t.Errorf("GET /todo/:id of a subtask after delete status = %d, want %d", w.Code, http.StatusNotFound)
+			}
+		}
+
+		doRequest(engine, http.MethodPost, "/trash/"+parent+"/restore", nil)
+		for _, id := range []string{first, second, grandchild} {
+			if w := doRequest(engine, http.MethodGet, "/todo/"+id, nil); w.Code != http.StatusOK {
+				t.Errorf("GET /todo/:id of a subtask after restore status = %d, want %d", w.Code, http.StatusOK)
+			}
+		}
+	})
+
+	t.Run("Restricts deleting items with subtasks", func(t *testing.T) {
+		engine := createEngineWithSubtaskDelete(controller.SubtaskDeleteRestrict)
+		parent := createItem(t, engine, gin.H{"title": "Parent", "dueDate": "2030-01-01T00:00:00Z"})
+		createItem(t, engine, gin.H{"title": "Child", "dueDate": "2030-01-01T00:00:00Z", "parentId": parent})
+
+		if w := doRequest(engine, http.MethodDelete, "/todo/"+parent, nil); w.Code != http.StatusConflict {
+			t.Errorf("DELETE /todo/:id status = %d, want %d", w.Code, http.StatusConflict)
+		}
+	})
+
+	t.Run("Orphans subtasks of deleted items", func(t *testing.T) {
+		engine := createEngineWithSubtaskDelete(controller.SubtaskDeleteOrphan)
+		parent := createItem(t, engine, gin.H{"title": "Parent", "dueDate": "2030-01-01T00:00:00Z"})
+		child := createItem(t, engine, gin.H{"title": "Child", "dueDate": "2030-01-01T00:00:00Z", "parentId": parent})
+
+		doRequest(engine, http.MethodDelete, "/todo/"+parent, nil)
+		var item db.TodoItemDb
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/todo/"+child, nil).Body.Bytes(), &item)
+		if item.Title != "Child" || item.ParentId != nil {
+			t.Errorf("GET /todo/:id of an orphaned subtask = %+v, want no parent", item)
+		}
+	})
+}
+
+func TestTodoItemController_Dependencies(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	design := createItem(t, engine, gin.H{"title": "Design", "dueDate": "2030-01-03T00:00:00Z"})
+	build := createItem(t, engine, gin.H{"title": "Build", "dueDate": "2030-01-01T00:00:00Z", "blockedBy": []string{design}})
+	ship := createItem(t, engine, gin.H{"title": "Ship", "dueDate": "2030-01-02T00:00:00Z", "blockedBy": []string{build}})
+	createItem(t, engine, gin.H{"title": "Unrelated", "dueDate": "2030-01-04T00:00:00Z"})
+
+	titles := func(path string) []string {
+		w := doRequest(engine, http.MethodGet, path, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, want %d", path, w.Code, http.StatusOK)
+		}
+
+		var page controller.TodoItemPlanBody
+		json.Unmarshal(w.Body.Bytes(), &page)
+		titles := []string{}
+		for _, item := range page.Items {
+			titles = append(titles, item.Title)
+		}
+		return titles
+	}
+
+	t.Run("Rejects unknown blockers and cycles", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/todo", gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z", "blockedBy": []string{"65a000000000000000000000"}})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /todo with an unknown blocker status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+
+		w = doPatch(engine, "/todo/"+design, controller.MergePatchContentType, `{"blockedBy": ["`+ship+`"]}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("PATCH /todo/:id with a cycle status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+		}
+
+		w = doPatch(engine, "/todo/"+design, controller.MergePatchContentType, `{"blockedBy": ["`+design+`"]}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("PATCH /todo/:id blocked by itself status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+		}
+	})
+
+	t.Run("Keeps blockers that were trashed", func(t *testing.T) {
+		engine := createEngine()
+		trashed := createItem(t, engine, gin.H{"title": "Trashed", "dueDate": "2030-01-01T00:00:00Z"})
+		other := createItem(t, engine, gin.H{"title": "Other", "dueDate": "2030-01-01T00:00:00Z"})
+		body := gin.H{"title": "Blocked", "dueDate": "2030-01-01T00:00:00Z", "blockedBy": []string{trashed}}
+		id := createItem(t, engine, body)
+		doRequest(engine, http.MethodDelete, "/todo/"+trashed, nil)
+
+		if w := doRequest(engine, http.MethodPut, "/todo/"+id, body); w.Code != http.StatusOK {
+			t.Errorf("PUT /todo/:id with a trashed blocker status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+		w := doPatch(engine, "/todo/"+id, controller.MergePatchContentType, `{"blockedBy": ["`+trashed+`", "`+other+`"]}`)
+		if w.Code != http.StatusOK {
+			t.Errorf("PATCH /todo/:id adding a blocker next to a trashed one status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+	})
+
+	t.Run("Lists blockers and dependents", func(t *testing.T) {
+		if got := titles("/todo/" + build + "/blockers"); !reflect.DeepEqual(got, []string{"Design"}) {
+			t.Errorf("GET /todo/:id/blockers = %v, want %v", got, []string{"Design"})
+		}
+		if got := titles("/todo/" + build + "/dependents"); !reflect.DeepEqual(got, []string{"Ship"}) {
+			t.Errorf("GET /todo/:id/dependents = %v, want %v", got, []string{"Ship"})
+		}
+		if got := titles("/todo/" + design + "/blockers"); len(got) != 0 {
+			t.Errorf("GET /todo/:id/blockers = %v, want none", got)
+		}
+	})
+
+	t.Run("Sorts the plan topologically", func(t *testing.T) {
+		if got, want := titles("/todo/plan"), []string{"Design", "Build", "Ship", "Unrelated"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /todo/plan = %v, want %v", got, want)
+		}
+		if got, want := titles("/todo/plan?ready=true"), []string{"Design", "Unrelated"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /todo/plan?ready=true = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Refuses to complete blocked items", func(t *testing.T) {
+		w := doPatch(engine, "/todo/"+build, controller.MergePatchContentType, `{"completed": true}`)
+		if w.Code != http.StatusConflict {
+			t.Errorf("PATCH /todo/:id of a blocked item status = %d, want %d", w.Code, http.StatusConflict)
+		}
+
+		w = doPatch(engine, "/todo/"+build+"?force=true", controller.MergePatchContentType, `{"completed": true}`)
+		if w.Code != http.StatusOK {
+			t.Errorf("PATCH /todo/:id?force=true status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		doPatch(engine, "/todo/"+design, controller.MergePatchContentType, `{"completed": true}`)
+		if got, want := titles("/todo/plan?ready=true"), []string{"Ship", "Unrelated"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /todo/plan?ready=true = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,370 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	// errInvalidList is returned when the list of an item doesn't exist
+	errInvalidList = errors.New("invalid list")
+	// errArchivedList is returned when adding items to an archived list
+	errArchivedList = errors.New("the list is archived")
+)
+
+type TodoListController struct {
+	TodoListDbHandler db.TodoListDbHandlerInterface
+	// Items serves the items of a list
+	Items              *TodoItemController
+	MaxReturnArraySize int
+}
+
+type NewTodoListBody struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+type MoveItemsBody struct {
+	Ids []string `json:"ids" binding:"required,min=1,dive,required"`
+}
+
+// TodoListPageBody is the response of GET /lists, clients pass Next as the "after" query param
+// to fetch the following page as long as Truncated is set
+type TodoListPageBody struct {
+	Lists     []db.TodoListDb `json:"lists"`
+	Next      string          `json:"next,omitempty"`
+	Truncated bool            `json:"truncated"`
+}
+
+// listObjectId converts the listId of a body, which was validated to be an object id or empty
+func listObjectId(hex string) *primitive.ObjectID {
+	if hex == "" {
+		return nil
+	}
+
+	id, _ := primitive.ObjectIDFromHex(hex)
+	return &id
+}
+
+// checkList makes sure the list exists, isn't archived and the user may edit it, an item without a list is always fine
+func (con *TodoItemController) checkList(c *gin.Context, listId *primitive.ObjectID) error {
+	if listId == nil {
+		return nil
+	}
+
+	list, err := con.TodoListDbHandler.FindOneById(c, *listId)
+	if err != nil {
+		return err
+	}
+
+	if list == nil {
+		return fmt.Errorf("%w: the list doesn't exist", errInvalidList)
+	}
+	if list.Archived {
+		return fmt.Errorf("%w, unarchive it before adding items", errArchivedList)
+	}
+	if !db.RoleAllows(db.ListRole(c, list), db.RoleEditor) {
+		return fmt.Errorf("%w: adding items to the list needs the %s role", errForbidden, db.RoleEditor)
+	}
+	return nil
+}
+
+// checkMove makes sure the user may move the item to the list. The roles on a list extend to its items, so moving needs
+// the owner role on the item, otherwise editors could make themselves its owner by moving it into a list of their own.
+func (con *TodoItemController) checkMove(c *gin.Context, item *db.TodoItemDb, listId *primitive.ObjectID) error {
+	if !db.RoleAllows(db.ItemRole(c, item), db.RoleOwner) {
+		return fmt.Errorf("%w: moving the item to another list needs the %s role", errForbidden, db.RoleOwner)
+	}
+	return con.checkList(c, listId)
+}
+
+// listStatus is the status to respond with for errors of checkList, invalidStatus is used for lists that don't exist
+func listStatus(err error, invalidStatus int) int {
+	switch {
+	case errors.Is(err, errInvalidList):
+		return invalidStatus
+	case errors.Is(err, errArchivedList):
+		return http.StatusConflict
+	case errors.Is(err, errForbidden):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// excludeArchived leaves the items of archived lists out of the filter
+func (con *TodoItemController) excludeArchived(c *gin.Context, filter *db.TodoItemFilter) error {
+	archived, err := con.TodoListDbHandler.FindAll(c, true, db.PageOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, list := range archived.Lists {
+		filter.ExcludeListIds = append(filter.ExcludeListIds, list.Id)
+	}
+	return nil
+}
+
+// findList reads the list of the path, it aborts the request when it isn't there
+func (con *TodoListController) findList(c *gin.Context) *db.TodoListDb {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return nil
+	}
+
+	list, err := con.TodoListDbHandler.FindOneById(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return nil
+	}
+
+	if list == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return nil
+	}
+
+	return list
+}
+
+func (con *TodoListController) Create(c *gin.Context) {
+	body := &NewTodoListBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := con.TodoListDbHandler.InsertOne(c, &db.TodoListDb{
+		Name:        body.Name,
+		Description: body.Description,
+	})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": id.Hex()})
+}
+
+func (con *TodoListController) FindOneById(c *gin.Context) {
+	list := con.findList(c)
+	if list == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// FindAll lists a page of the lists by name, the "archived" query param lists the archived ones instead.
+// It accepts the "limit" and "after" query params of the item endpoints.
+func (con *TodoListController) FindAll(c *gin.Context) {
+	archived := false
+	if archivedString, ok := c.GetQuery("archived"); ok {
+		var err error
+		if archived, err = strconv.ParseBool(archivedString); err != nil {
+			c.AbortWithError(http.StatusBadRequest, fmt.Errorf("archived must be a boolean"))
+			return
+		}
+	}
+
+	options, err := con.listPageOptions(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	page, err := con.TodoListDbHandler.FindAll(c, archived, options)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	body := &TodoListPageBody{Lists: page.Lists, Truncated: page.Truncated}
+	if page.Next != nil {
+		if body.Next, err = page.Next.Token(); err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, body)
+}
+
+// listPageOptions reads the "limit" and "after" query params, the limit defaults to and is capped by MaxReturnArraySize
+func (con *TodoListController) listPageOptions(c *gin.Context) (db.PageOptions, error) {
+	page := db.PageOptions{Limit: con.MaxReturnArraySize}
+
+	if limitString, ok := c.GetQuery("limit"); ok {
+		limit, err := strconv.Atoi(limitString)
+		if err != nil || limit <= 0 {
+			return page, fmt.Errorf("limit must be a positive integer")
+		}
+		page.Limit = min(limit, con.MaxReturnArraySize)
+	}
+
+	if token := c.Query("after"); token != "" {
+		after, err := db.ParseListPageToken(token)
+		if err != nil {
+			return page, err
+		}
+		page.After = after
+	}
+
+	return page, nil
+}
+
+// UpdateByID replaces the name and description of the list
+func (con *TodoListController) UpdateByID(c *gin.Context) {
+	list := con.findList(c)
+	if list == nil || !allowed(c, db.ListRole(c, list), db.RoleEditor) {
+		return
+	}
+
+	body := &NewTodoListBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	con.update(c, list.Id, &db.TodoListUpdate{Name: &body.Name, Description: &body.Description})
+}
+
+// Archive hides the list and its items from the listings, the items stay reachable through the list
+func (con *TodoListController) Archive(c *gin.Context) {
+	list := con.findList(c)
+	if list == nil || !allowed(c, db.ListRole(c, list), db.RoleOwner) {
+		return
+	}
+
+	archived := true
+	con.update(c, list.Id, &db.TodoListUpdate{Archived: &archived})
+}
+
+func (con *TodoListController) Unarchive(c *gin.Context) {
+	list := con.findList(c)
+	if list == nil || !allowed(c, db.ListRole(c, list), db.RoleOwner) {
+		return
+	}
+
+	archived := false
+	con.update(c, list.Id, &db.TodoListUpdate{Archived: &archived})
+}
+
+func (con *TodoListController) update(c *gin.Context, id primitive.ObjectID, update *db.TodoListUpdate) {
+	list, err := con.TodoListDbHandler.UpdateOneById(c, id, update)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if list == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// DeleteOneById deletes the list, which has to be empty, items in the trash don't count
+func (con *TodoListController) DeleteOneById(c *gin.Context) {
+	list := con.findList(c)
+	if list == nil || !allowed(c, db.ListRole(c, list), db.RoleOwner) {
+		return
+	}
+
+	page, err := con.Items.TodoItemDbHandler.FindAll(c, db.TodoItemFilter{ListIds: []primitive.ObjectID{list.Id}}, db.PageOptions{Limit: 1})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if len(page.Items) > 0 {
+		c.AbortWithError(http.StatusConflict, fmt.Errorf("the list still has items, move or delete them first"))
+		return
+	}
+
+	if err := con.TodoListDbHandler.DeleteOneById(c, list.Id); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// FindItems lists the items of the list, archived or not, it accepts the same query params as the item listing
+func (con *TodoListController) FindItems(c *gin.Context) {
+	list := con.findList(c)
+	if list == nil {
+		return
+	}
+
+	filter, err := todoItemFilter(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	filter.ListIds = []primitive.ObjectID{list.Id}
+
+	con.Items.findPage(c, filter)
+}
+
+// MoveItems moves the items of the body the user owns into the list, it responds with the moved items,
+// the ids that don't exist and the ids of the items the user doesn't own
+func (con *TodoListController) MoveItems(c *gin.Context) {
+	list := con.findList(c)
+	if list == nil || !allowed(c, db.ListRole(c, list), db.RoleEditor) {
+		return
+	}
+
+	if list.Archived {
+		c.AbortWithError(http.StatusConflict, fmt.Errorf("%w, unarchive it before adding items", errArchivedList))
+		return
+	}
+
+	body := &MoveItemsBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	if len(body.Ids) > con.MaxReturnArraySize {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("at most %d items can be moved at once", con.MaxReturnArraySize))
+		return
+	}
+
+	ids := make([]primitive.ObjectID, len(body.Ids))
+	for i, idString := range body.Ids {
+		id, err := primitive.ObjectIDFromHex(idString)
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id %q", idString))
+			return
+		}
+		ids[i] = id
+	}
+
+	// see checkMove for why moving needs the owner role
+	owned, missing, forbidden, err := con.Items.allowedIds(c, ids, db.RoleOwner)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := con.Items.TodoItemDbHandler.MoveToList(c, owned, list.Id); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	page, err := con.Items.TodoItemDbHandler.FindAll(c, db.TodoItemFilter{Ids: owned}, db.PageOptions{})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": page.Items, "missing": missing, "forbidden": forbidden})
+}
@@ -0,0 +1,217 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"todo-list-service/pkg/controller"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createList(t *testing.T, engine *gin.Engine, name string) string {
+	w := doRequest(engine, http.MethodPost, "/lists", gin.H{"name": name})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /lists status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+
+	var res struct {
+		Id string `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	return res.Id
+}
+
+func itemTitles(t *testing.T, engine *gin.Engine, path string) []string {
+	w := doRequest(engine, http.MethodGet, path, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want %d", path, w.Code, http.StatusOK)
+	}
+
+	var page controller.TodoItemPageBody
+	json.Unmarshal(w.Body.Bytes(), &page)
+	titles := []string{}
+	for _, item := range page.Items {
+		titles = append(titles, item.Title)
+	}
+	return titles
+}
+
+func TestTodoListController_CRUD(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	work := createList(t, engine, "Work")
+	createList(t, engine, "Home")
+
+	t.Run("Rejects a list without name", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/lists", gin.H{"description": "Test_Description"})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /lists status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Lists by name", func(t *testing.T) {
+		var page controller.TodoListPageBody
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/lists", nil).Body.Bytes(), &page)
+
+		names := []string{}
+		for _, list := range page.Lists {
+			names = append(names, list.Name)
+		}
+		if want := []string{"Home", "Work"}; !reflect.DeepEqual(names, want) {
+			t.Errorf("GET /lists = %v, want %v", names, want)
+		}
+	})
+
+	t.Run("Pages through the lists", func(t *testing.T) {
+		var page controller.TodoListPageBody
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/lists?limit=1", nil).Body.Bytes(), &page)
+		if len(page.Lists) != 1 || page.Lists[0].Name != "Home" || !page.Truncated || page.Next == "" {
+			t.Fatalf("GET /lists?limit=1 = %+v, want Home and a next page", page)
+		}
+
+		var next controller.TodoListPageBody
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/lists?limit=1&after="+page.Next, nil).Body.Bytes(), &next)
+		if len(next.Lists) != 1 || next.Lists[0].Name != "Work" || next.Truncated || next.Next != "" {
+			t.Errorf("GET /lists?after= = %+v, want Work and no next page", next)
+		}
+
+		if w := doRequest(engine, http.MethodGet, "/lists?after=invalid", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("GET /lists?after=invalid status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Replaces a list", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPut, "/lists/"+work, gin.H{"name": "Office", "description": "Test_Description"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("PUT /lists/:id status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var list db.TodoListDb
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/lists/"+work, nil).Body.Bytes(), &list)
+		if list.Name != "Office" || list.Description != "Test_Description" {
+			t.Errorf("GET /lists/:id = %+v, want the replaced list", list)
+		}
+	})
+
+	t.Run("Only deletes empty lists", func(t *testing.T) {
+		id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z", "listId": work})
+
+		w := doRequest(engine, http.MethodDelete, "/lists/"+work, nil)
+		if w.Code != http.StatusConflict {
+			t.Errorf("DELETE /lists/:id of a list with items status = %d, want %d", w.Code, http.StatusConflict)
+		}
+
+		doPatch(engine, "/todo/"+id, controller.MergePatchContentType, `{"listId": ""}`)
+		w = doRequest(engine, http.MethodDelete, "/lists/"+work, nil)
+		if w.Code != http.StatusNoContent {
+			t.Errorf("DELETE /lists/:id status = %d, want %d", w.Code, http.StatusNoContent)
+		}
+
+		w = doRequest(engine, http.MethodGet, "/lists/"+work, nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET /lists/:id of a deleted list status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestTodoListController_Items(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	work := createList(t, engine, "Work")
+	home := createList(t, engine, "Home")
+
+	report := createItem(t, engine, gin.H{"title": "Report", "dueDate": "2030-01-01T00:00:00Z", "listId": work, "labels": []string{"urgent"}})
+	createItem(t, engine, gin.H{"title": "Meeting", "dueDate": "2030-01-02T00:00:00Z", "listId": work})
+	dishes := createItem(t, engine, gin.H{"title": "Dishes", "dueDate": "2030-01-03T00:00:00Z"})
+
+	t.Run("Rejects unknown lists", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/todo", gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z", "listId": "65a000000000000000000000"})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /todo with an unknown list status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+
+		w = doPatch(engine, "/todo/"+dishes, controller.MergePatchContentType, `{"listId": "65a000000000000000000000"}`)
+		if w.Code != http.StatusUnprocessableEntity {
+			t.Errorf("PATCH /todo/:id with an unknown list status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+		}
+	})
+
+	t.Run("Lists the items of a list with the usual filters", func(t *testing.T) {
+		if got, want := itemTitles(t, engine, "/lists/"+work+"/todo"), []string{"Report", "Meeting"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /lists/:id/todo = %v, want %v", got, want)
+		}
+		if got, want := itemTitles(t, engine, "/lists/"+work+"/todo?label=urgent"), []string{"Report"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /lists/:id/todo?label=urgent = %v, want %v", got, want)
+		}
+		if got, want := itemTitles(t, engine, "/lists/"+work+"/todo?limit=1"), []string{"Report"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /lists/:id/todo?limit=1 = %v, want %v", got, want)
+		}
+
+		w := doRequest(engine, http.MethodGet, "/lists/65a000000000000000000000/todo", nil)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("GET /lists/:id/todo of an unknown list status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Moves items between lists", func(t *testing.T) {
+		missing := "65a000000000000000000000"
+		w := doRequest(engine, http.MethodPost, "/lists/"+home+"/todo", gin.H{"ids": []string{report, dishes, missing}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST /lists/:id/todo status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		var res struct {
+			Missing []string `json:"missing"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		if !reflect.DeepEqual(res.Missing, []string{missing}) {
+			t.Errorf("POST /lists/:id/todo missing = %v, want %v", res.Missing, []string{missing})
+		}
+
+		if got, want := itemTitles(t, engine, "/lists/"+home+"/todo"), []string{"Report", "Dishes"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /lists/:id/todo = %v, want %v", got, want)
+		}
+		if got, want := itemTitles(t, engine, "/lists/"+work+"/todo"), []string{"Meeting"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /lists/:id/todo = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Archiving hides a list and its items", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/lists/"+work+"/archive", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("POST /lists/:id/archive status = %d, want %d", w.Code, http.StatusOK)
+		}
+
+		if got, want := itemTitles(t, engine, "/todo"), []string{"Report", "Dishes"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /todo = %v, want %v", got, want)
+		}
+		if got, want := itemTitles(t, engine, "/lists/"+work+"/todo"), []string{"Meeting"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /lists/:id/todo of an archived list = %v, want %v", got, want)
+		}
+
+		var page controller.TodoListPageBody
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/lists?archived=true", nil).Body.Bytes(), &page)
+		if len(page.Lists) != 1 || page.Lists[0].Name != "Work" {
+			t.Errorf("GET /lists?archived=true = %v, want Work", page.Lists)
+		}
+
+		w = doRequest(engine, http.MethodPost, "/lists/"+work+"/todo", gin.H{"ids": []string{dishes}})
+		if w.Code != http.StatusConflict {
+			t.Errorf("POST /lists/:id/todo of an archived list status = %d, want %d", w.Code, http.StatusConflict)
+		}
+		w = doPatch(engine, "/todo/"+dishes, controller.MergePatchContentType, `{"listId": "`+work+`"}`)
+		if w.Code != http.StatusConflict {
+			t.Errorf("PATCH /todo/:id into an archived list status = %d, want %d", w.Code, http.StatusConflict)
+		}
+
+		doRequest(engine, http.MethodPost, "/lists/"+work+"/unarchive", nil)
+		if got, want := itemTitles(t, engine, "/todo"), []string{"Report", "Meeting", "Dishes"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("GET /todo = %v, want %v", got, want)
+		}
+	})
+}
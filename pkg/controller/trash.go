@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FindTrash lists the items in the trash, it accepts the same query params as FindAll
+func (con *TodoItemController) FindTrash(c *gin.Context) {
+	filter, err := todoItemFilter(c)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+	filter.Trashed = true
+
+	con.findPage(c, filter)
+}
+
+// RestoreFromTrash takes the item out of the trash along with the subtasks that were trashed with it,
+// and responds with the restored item. The If-Match header makes the restore conditional on the version of the item
+func (con *TodoItemController) RestoreFromTrash(c *gin.Context) {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
+
+	trashed, err := con.TodoItemDbHandler.FindOneById(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if trashed == nil || trashed.DeletedAt == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if !allowed(c, db.ItemRole(c, trashed), db.RoleOwner) {
+		return
+	}
+
+	item, err := con.TodoItemDbHandler.UntrashOneById(c, id, version)
+	if errors.Is(err, db.ErrVersionConflict) {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if item == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if err := con.restoreSubtree(c, id, *trashed.DeletedAt); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	respondWithItem(c, http.StatusOK, item)
+}
+
+// DeletePermanently deletes an item that is in the trash, items that aren't have to be trashed first.
+// The If-Match header makes the delete conditional on the version of the item.
+func (con *TodoItemController) DeletePermanently(c *gin.Context) {
+	idString := c.GetString("id")
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		c.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to decode id"))
+		return
+	}
+
+	version, err := ifMatchVersion(c)
+	if err != nil {
+		c.AbortWithError(http.StatusPreconditionFailed, err)
+		return
+	}
+
+	item, err := con.TodoItemDbHandler.FindOneById(c, id)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if item == nil || item.DeletedAt == nil {
+		c.AbortWithStatus(http.StatusNotFound)
+		return
+	}
+
+	if !allowed(c, db.ItemRole(c, item), db.RoleOwner) {
+		return
+	}
+
+	if version != db.AnyVersion && version != item.Version {
+		c.AbortWithError(http.StatusPreconditionFailed, db.ErrVersionConflict)
+		return
+	}
+
+	// deleting only the version that was read makes sure the item wasn't restored in the meantime
+	err = con.TodoItemDbHandler.DeleteOneById(c, id, item.Version)
+	if errors.Is(err, db.ErrVersionConflict) {
+		status := http.StatusConflict
+		if version != db.AnyVersion {
+			status = http.StatusPreconditionFailed
+		}
+		c.AbortWithError(status, err)
+		return
+	}
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.AbortWithStatus(http.StatusNoContent)
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserController struct {
+	UserDbHandler db.UserDbHandlerInterface
+}
+
+type NewUserBody struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// Create registers a new user, its id identifies the user on the other endpoints
+func (con *UserController) Create(c *gin.Context) {
+	body := &NewUserBody{}
+	if err := c.ShouldBindJSON(body); err != nil {
+		c.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	id, err := con.UserDbHandler.InsertOne(c, &db.UserDb{
+		Name:      body.Name,
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	})
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": id.Hex()})
+}
+
+// Me responds with the calling user
+func (con *UserController) Me(c *gin.Context) {
+	c.JSON(http.StatusOK, c.MustGet("user"))
+}
@@ -0,0 +1,177 @@
+package controller_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	"todo-list-service/pkg/controller"
+	"todo-list-service/pkg/db"
+	"todo-list-service/pkg/jwt"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUserController(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+
+	t.Run("Registers a user", func(t *testing.T) {
+		w := doRequest(engine, http.MethodPost, "/users", gin.H{"name": "Test_Name"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST /users status = %d, want %d", w.Code, http.StatusCreated)
+		}
+
+		var res struct {
+			Id string `json:"id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &res)
+
+		var user db.UserDb
+		w = doRequestWithHeaders(engine, http.MethodGet, "/users/me", nil, map[string]string{middleware.UserHeader: res.Id})
+		json.Unmarshal(w.Body.Bytes(), &user)
+		if w.Code != http.StatusOK || user.Name != "Test_Name" {
+			t.Errorf("GET /users/me = %d %+v, want the registered user", w.Code, user)
+		}
+	})
+
+	t.Run("Rejects unknown users", func(t *testing.T) {
+		for _, user := range []string{"", "not-an-id", "65b0000000000000000000ff"} {
+			w := doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, map[string]string{middleware.UserHeader: user})
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("GET /todo as %q status = %d, want %d", user, w.Code, http.StatusUnauthorized)
+			}
+		}
+	})
+}
+
+func TestTodoItemController_Ownership(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngine()
+	id := createItem(t, engine, gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z"})
+	list := createList(t, engine, "Work")
+	other := map[string]string{middleware.UserHeader: otherUser}
+
+	t.Run("Items are owned by their creator", func(t *testing.T) {
+		var item db.TodoItemDb
+		json.Unmarshal(doRequest(engine, http.MethodGet, "/todo/"+id, nil).Body.Bytes(), &item)
+		if item.Owner.Hex() != testUser {
+			t.Errorf("GET /todo/:id owner = %v, want %v", item.Owner.Hex(), testUser)
+		}
+	})
+
+	t.Run("Other users don't see the items", func(t *testing.T) {
+		var page controller.TodoItemPageBody
+		w := doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, other)
+		json.Unmarshal(w.Body.Bytes(), &page)
+		if w.Code != http.StatusOK || len(page.Items) != 0 {
+			t.Errorf("GET /todo of another user = %d %s, want no items", w.Code, w.Body)
+		}
+
+		for _, path := range []string{"/todo/" + id, "/todo/" + id + "/history", "/lists/" + list} {
+			if w := doRequestWithHeaders(engine, http.MethodGet, path, nil, other); w.Code != http.StatusNotFound {
+				t.Errorf("GET %s of another user status = %d, want %d", path, w.Code, http.StatusNotFound)
+			}
+		}
+	})
+
+	t.Run("Other users can't change the items", func(t *testing.T) {
+		body := gin.H{"title": "Changed", "dueDate": "2030-01-01T00:00:00Z"}
+		if w := doRequestWithHeaders(engine, http.MethodPut, "/todo/"+id, body, other); w.Code != http.StatusNotFound {
+			t.Errorf("PUT /todo/:id of another user status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+		if w := doRequestWithHeaders(engine, http.MethodDelete, "/todo/"+id, nil, other); w.Code != http.StatusNotFound {
+			t.Errorf("DELETE /todo/:id of another user status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+
+		w := doRequestWithHeaders(engine, http.MethodPost, "/todo", gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z", "parentId": id}, other)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("POST /todo with the parent of another user status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+
+		if w := doRequest(engine, http.MethodGet, "/todo/"+id, nil); w.Code != http.StatusOK {
+			t.Errorf("GET /todo/:id status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+}
+
+// signToken creates an HS256 token for the claims
+func signToken(secret string, claims gin.H) string {
+	header, _ := json.Marshal(gin.H{"alg": jwt.HS256, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	t.Parallel()
+
+	engine := createEngineWith(controller.SubtaskDeleteTrash, &jwt.Verifier{
+		Keys:     []jwt.Key{{Secret: []byte("Test_Secret")}},
+		Issuer:   "https://issuer.example",
+		Audience: "todo",
+	})
+	claims := func(sub string, exp time.Duration) gin.H {
+		return gin.H{"sub": sub, "name": "Test_Name", "iss": "https://issuer.example", "aud": "todo", "exp": time.Now().Add(exp).Unix()}
+	}
+	bearer := func(token string) map[string]string {
+		return map[string]string{"Authorization": "Bearer " + token}
+	}
+
+	t.Run("Challenges requests without a token", func(t *testing.T) {
+		w := doRequest(engine, http.MethodGet, "/todo", nil)
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") != `Bearer realm="todo-list-service"` {
+			t.Errorf("GET /todo without a token = %d %q, want %d with a challenge", w.Code, w.Header().Get("WWW-Authenticate"), http.StatusUnauthorized)
+		}
+
+		if w := doRequest(engine, http.MethodPost, "/users", gin.H{"name": "Test_Name"}); w.Code != http.StatusNotFound {
+			t.Errorf("POST /users status = %d, want %d", w.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Rejects invalid tokens", func(t *testing.T) {
+		for name, token := range map[string]string{
+			"expired":      signToken("Test_Secret", claims("user-1", -time.Hour)),
+			"wrong secret": signToken("Other_Secret", claims("user-1", time.Hour)),
+			"malformed":    "not-a-token",
+		} {
+			w := doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, bearer(token))
+			if w.Code != http.StatusUnauthorized || !strings.Contains(w.Header().Get("WWW-Authenticate"), `error="invalid_token"`) {
+				t.Errorf("GET /todo with an %s token = %d %q, want %d with invalid_token", name, w.Code, w.Header().Get("WWW-Authenticate"), http.StatusUnauthorized)
+			}
+		}
+	})
+
+	t.Run("Maps the subject to a user", func(t *testing.T) {
+		token := bearer(signToken("Test_Secret", claims("user-1", time.Hour)))
+
+		var first, second db.UserDb
+		json.Unmarshal(doRequestWithHeaders(engine, http.MethodGet, "/users/me", nil, token).Body.Bytes(), &first)
+		json.Unmarshal(doRequestWithHeaders(engine, http.MethodGet, "/users/me", nil, token).Body.Bytes(), &second)
+		if first.Subject != "user-1" || first.Name != "Test_Name" || first.Id != second.Id {
+			t.Errorf("GET /users/me = %+v and %+v, want the same user for the subject", first, second)
+		}
+
+		w := doRequestWithHeaders(engine, http.MethodPost, "/todo", gin.H{"title": "Test_Title", "dueDate": "2030-01-01T00:00:00Z"}, token)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("POST /todo status = %d, want %d", w.Code, http.StatusCreated)
+		}
+
+		var page controller.TodoItemPageBody
+		other := bearer(signToken("Test_Secret", claims("user-2", time.Hour)))
+		json.Unmarshal(doRequestWithHeaders(engine, http.MethodGet, "/todo", nil, other).Body.Bytes(), &page)
+		if len(page.Items) != 0 {
+			t.Errorf("GET /todo of another subject = %v, want no items", page.Items)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ApiKeyMemoryDbHandler is an in-process implementation of ApiKeyDbHandlerInterface. The zero value is ready to use.
+type ApiKeyMemoryDbHandler struct {
+	mu   sync.RWMutex
+	keys map[primitive.ObjectID]ApiKeyDb
+}
+
+func (h *ApiKeyMemoryDbHandler) InsertOne(context context.Context, new *ApiKeyDb) (primitive.ObjectID, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.keys == nil {
+		h.keys = map[primitive.ObjectID]ApiKeyDb{}
+	}
+
+	key := *new
+	key.Owner = ownerOf(context, key.Owner)
+	if key.Id.IsZero() {
+		key.Id = primitive.NewObjectID()
+	}
+	h.keys[key.Id] = key
+
+	return key.Id, nil
+}
+
+func (h *ApiKeyMemoryDbHandler) FindAll(context context.Context) ([]ApiKeyDb, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	keys := []ApiKeyDb{}
+	for _, key := range h.keys {
+		if owns(context, key.Owner) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.SortFunc(keys, func(a, b ApiKeyDb) int { return strings.Compare(a.Id.Hex(), b.Id.Hex()) })
+	return keys, nil
+}
+
+func (h *ApiKeyMemoryDbHandler) FindOneById(context context.Context, id primitive.ObjectID) (*ApiKeyDb, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	key, ok := h.keys[id]
+	if !ok || !owns(context, key.Owner) {
+		return nil, nil
+	}
+	return &key, nil
+}
+
+func (h *ApiKeyMemoryDbHandler) FindOneByHash(context context.Context, hash string) (*ApiKeyDb, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, key := range h.keys {
+		if key.Hash == hash {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (h *ApiKeyMemoryDbHandler) Rotate(context context.Context, id primitive.ObjectID, prefix string, hash string) (*ApiKeyDb, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key, ok := h.keys[id]
+	if !ok || !owns(context, key.Owner) {
+		return nil, nil
+	}
+
+	key.Prefix = prefix
+	key.Hash = hash
+	key.LastUsedAt = nil
+	h.keys[id] = key
+
+	return &key, nil
+}
+
+func (h *ApiKeyMemoryDbHandler) Touch(context context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if key, ok := h.keys[id]; ok {
+		key.LastUsedAt = &usedAt
+		h.keys[id] = key
+	}
+	return nil
+}
+
+func (h *ApiKeyMemoryDbHandler) DeleteOneById(context context.Context, id primitive.ObjectID) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if key, ok := h.keys[id]; ok && owns(context, key.Owner) {
+		delete(h.keys, id)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scopes of api keys
+const (
+	// ApiKeyScopeRead only allows reading
+	ApiKeyScopeRead = "read"
+	// ApiKeyScopeWrite allows everything the owner of the key can do, except managing api keys
+	ApiKeyScopeWrite = "write"
+)
+
+// ApiKeyDbHandlerInterface stores the api keys of users, scoped to the user stored in the context by WithOwner
+type ApiKeyDbHandlerInterface interface {
+	InsertOne(context.Context, *ApiKeyDb) (primitive.ObjectID, error)
+	// FindAll returns the keys ordered by creation
+	FindAll(context.Context) ([]ApiKeyDb, error)
+	// FindOneById returns the key, or nil when it doesn't exist
+	FindOneById(context.Context, primitive.ObjectID) (*ApiKeyDb, error)
+	// FindOneByHash returns the key with the hash of HashApiKey, or nil when there is none. It is never scoped to an owner.
+	FindOneByHash(context.Context, string) (*ApiKeyDb, error)
+	// Rotate replaces the secret of the key and returns the updated key, or nil when it doesn't exist
+	Rotate(ctx context.Context, id primitive.ObjectID, prefix string, hash string) (*ApiKeyDb, error)
+	// Touch sets the time the key was last used
+	Touch(context.Context, primitive.ObjectID, time.Time) error
+	DeleteOneById(context.Context, primitive.ObjectID) error
+}
+
+// ApiKeyDb is an api key of a user, only the hash of the secret is stored and Prefix tells keys apart
+type ApiKeyDb struct {
+	Id         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Owner      primitive.ObjectID `bson:"owner" json:"owner"`
+	Name       string             `bson:"name" json:"name"`
+	Scope      string             `bson:"scope" json:"scope"`
+	Prefix     string             `bson:"prefix" json:"prefix"`
+	Hash       string             `bson:"hash" json:"-"`
+	CreatedAt  time.Time          `bson:"createdAt" json:"createdAt"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+}
+
+// HashApiKey is the hash api keys are stored and looked up by. Keys are random, so a fast hash is fine.
+func HashApiKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// DefaultApiKeysCollection is the collection of the api keys when ApiKeyDbHandler.Collection is empty
+const DefaultApiKeysCollection = "api_keys"
+
+// ApiKeyDbHandler is the mongo backed implementation of ApiKeyDbHandlerInterface
+type ApiKeyDbHandler struct {
+	// Collection is the name of the collection the api keys are stored in, DefaultApiKeysCollection when empty
+	Collection string
+
+	coll *mongo.Collection
+}
+
+func (h *ApiKeyDbHandler) New(context context.Context, database *mongo.Database) error {
+	h.coll = database.Collection(orDefault(h.Collection, DefaultApiKeysCollection))
+
+	_, err := h.coll.Indexes().CreateMany(context, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "owner", Value: 1}}},
+	})
+	return err
+}
+
+func (h *ApiKeyDbHandler) InsertOne(context context.Context, new *ApiKeyDb) (primitive.ObjectID, error) {
+	key := *new
+	key.Owner = ownerOf(context, key.Owner)
+
+	result, err := h.coll.InsertOne(context, &key)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (h *ApiKeyDbHandler) FindAll(context context.Context) ([]ApiKeyDb, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cur, err := h.coll.Find(context, ownedBy(context, bson.D{}), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []ApiKeyDb{}
+	if err := cur.All(context, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (h *ApiKeyDbHandler) FindOneById(context context.Context, id primitive.ObjectID) (*ApiKeyDb, error) {
+	return h.findOne(context, ownedBy(context, bson.D{{Key: "_id", Value: id}}))
+}
+
+func (h *ApiKeyDbHandler) FindOneByHash(context context.Context, hash string) (*ApiKeyDb, error) {
+	return h.findOne(context, bson.D{{Key: "hash", Value: hash}})
+}
+
+func (h *ApiKeyDbHandler) findOne(context context.Context, filter bson.D) (*ApiKeyDb, error) {
+	var key ApiKeyDb
+	err := h.coll.FindOne(context, filter).Decode(&key)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (h *ApiKeyDbHandler) Rotate(context context.Context, id primitive.ObjectID, prefix string, hash string) (*ApiKeyDb, error) {
+	update := bson.M{"$set": bson.M{"prefix": prefix, "hash": hash}, "$unset": bson.M{"lastUsedAt": ""}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var key ApiKeyDb
+	err := h.coll.FindOneAndUpdate(context, ownedBy(context, bson.D{{Key: "_id", Value: id}}), update, opts).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (h *ApiKeyDbHandler) Touch(context context.Context, id primitive.ObjectID, usedAt time.Time) error {
+	_, err := h.coll.UpdateOne(context, bson.D{{Key: "_id", Value: id}}, bson.M{"$set": bson.M{"lastUsedAt": usedAt}})
+	return err
+}
+
+func (h *ApiKeyDbHandler) DeleteOneById(context context.Context, id primitive.ObjectID) error {
+	_, err := h.coll.DeleteOne(context, ownedBy(context, bson.D{{Key: "_id", Value: id}}))
+	return err
+}
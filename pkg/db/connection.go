@@ -2,25 +2,46 @@ package db
 
 import (
 	"context"
-	"fmt"
+	"log/slog"
+	"sync"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// DefaultDatabase is the database used when Connection.DatabaseName is empty
+const DefaultDatabase = "ArticleManagement"
+
 // Connection represents a connection to the MongoDB database.
 type Connection struct {
 	client   *mongo.Client
 	Database *mongo.Database
 	Close    func()
+	// DatabaseName is the database to use, DefaultDatabase when empty
+	DatabaseName string
+	// PoolMonitor is notified of the events of the connection pool when it is set before connecting
+	PoolMonitor *event.PoolMonitor
+	// TracerProvider records a span for every command when it is set before connecting
+	TracerProvider trace.TracerProvider
 }
 
 // Connect initializes the MongoDB connection and sets up the database.
 func (c *Connection) Connect(mongoUri string) error {
 	// Configure MongoDB client options.
 	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().ApplyURI(mongoUri).SetServerAPIOptions(serverAPI)
+	monitor := commandLogger()
+	if c.TracerProvider != nil {
+		monitor = joinMonitors(monitor, otelmongo.NewMonitor(otelmongo.WithTracerProvider(c.TracerProvider)))
+	}
+	opts := options.Client().ApplyURI(mongoUri).SetServerAPIOptions(serverAPI).SetMonitor(monitor)
+	if c.PoolMonitor != nil {
+		opts.SetPoolMonitor(c.PoolMonitor)
+	}
 
 	// Connect to the MongoDB server.
 	var err error
@@ -34,7 +55,7 @@ func (c *Connection) Connect(mongoUri string) error {
 	if err := c.client.Database("admin").RunCommand(context.TODO(), bson.D{{Key: "ping", Value: 1}}).Decode(&result); err != nil {
 		return err
 	}
-	fmt.Println("Pinged your deployment. You successfully connected to MongoDB!")
+	slog.Info("connected to mongo")
 
 	// Define the function to close the connection and stop the server.
 	c.Close = func() {
@@ -42,6 +63,72 @@ func (c *Connection) Connect(mongoUri string) error {
 	}
 
 	// Set the database to use.
-	c.Database = c.client.Database("ArticleManagement")
+	c.Database = c.client.Database(orDefault(c.DatabaseName, DefaultDatabase))
 	return nil
 }
+
+// Ping checks that the server is reachable, the context bounds how long it may take
+func (c *Connection) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx, readpref.Primary())
+}
+
+// commandLogger logs every mongo command at debug level with its duration, failed commands are logged as warnings.
+// The records carry the request id of the context the command was run with.
+func commandLogger() *event.CommandMonitor {
+	// the collection is only part of the started event
+	collections := sync.Map{}
+
+	finished := func(ctx context.Context, level slog.Level, e *event.CommandFinishedEvent, attrs ...slog.Attr) {
+		collection, _ := collections.LoadAndDelete(e.RequestID)
+		attrs = append(attrs,
+			slog.String("command", e.CommandName),
+			slog.String("database", e.DatabaseName),
+			slog.Any("collection", collection),
+			slog.Duration("duration", e.Duration),
+		)
+		slog.LogAttrs(ctx, level, "mongo command", attrs...)
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			if collection, ok := e.Command.Lookup(e.CommandName).StringValueOK(); ok {
+				collections.Store(e.RequestID, collection)
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			finished(ctx, slog.LevelDebug, &e.CommandFinishedEvent)
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			finished(ctx, slog.LevelWarn, &e.CommandFinishedEvent, slog.String("error", e.Failure))
+		},
+	}
+}
+
+// orDefault returns the name, or the default when it is empty
+func orDefault(name, def string) string {
+	if name == "" {
+		return def
+	}
+	return name
+}
+
+// joinMonitors notifies every monitor of the command events, in order
+func joinMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				m.Started(ctx, e)
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				m.Succeeded(ctx, e)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				m.Failed(ctx, e)
+			}
+		},
+	}
+}
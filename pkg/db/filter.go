@@ -0,0 +1,170 @@
+package db
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TodoItemFilter narrows down the items returned by FindAll, zero values don't filter
+type TodoItemFilter struct {
+	Ids       []primitive.ObjectID
+	Completed *bool
+	// DueBefore and DueAfter are exclusive bounds on the due date
+	DueBefore *time.Time
+	DueAfter  *time.Time
+	Labels    []string
+	// MatchAllLabels requires every label instead of any of them
+	MatchAllLabels bool
+	// Title matches a case-insensitive substring of the title
+	Title string
+	// ParentIds only returns the children of the given items
+	ParentIds []primitive.ObjectID
+	// ListIds only returns the items of the given lists, ExcludeListIds leaves out the items of the given lists
+	ListIds        []primitive.ObjectID
+	ExcludeListIds []primitive.ObjectID
+	// BlockedBy only returns the items blocked by any of the given items
+	BlockedBy []primitive.ObjectID
+	// Trashed returns only the items in the trash instead of leaving them out
+	Trashed bool
+	// DeletedBefore is an exclusive bound on the time items were moved to the trash
+	DeletedBefore *time.Time
+}
+
+// bson converts the filter into a mongo query
+func (f *TodoItemFilter) bson() bson.M {
+	filter := bson.M{}
+
+	if len(f.Ids) > 0 {
+		filter["_id"] = bson.M{"$in": f.Ids}
+	}
+
+	if len(f.ParentIds) > 0 {
+		filter["parentId"] = bson.M{"$in": f.ParentIds}
+	}
+
+	list := bson.M{}
+	if len(f.ListIds) > 0 {
+		list["$in"] = f.ListIds
+	}
+	if len(f.ExcludeListIds) > 0 {
+		list["$nin"] = f.ExcludeListIds
+	}
+	if len(list) > 0 {
+		filter["listId"] = list
+	}
+
+	if len(f.BlockedBy) > 0 {
+		filter["blockedBy"] = bson.M{"$in": f.BlockedBy}
+	}
+
+	if f.Completed != nil {
+		if *f.Completed {
+			filter["completed"] = true
+		} else {
+			// completed is omitted when false, so the field can be missing
+			filter["completed"] = bson.M{"$ne": true}
+		}
+	}
+
+	due := bson.M{}
+	if f.DueBefore != nil {
+		due["$lt"] = *f.DueBefore
+	}
+	if f.DueAfter != nil {
+		due["$gt"] = *f.DueAfter
+	}
+	if len(due) > 0 {
+		filter["dueDate"] = due
+	}
+
+	if len(f.Labels) > 0 {
+		operator := "$in"
+		if f.MatchAllLabels {
+			operator = "$all"
+		}
+		filter["labels"] = bson.M{operator: f.Labels}
+	}
+
+	if f.Title != "" {
+		filter["$expr"] = bson.M{"$regexMatch": bson.M{"input": titleExpr, "regex": regexp.QuoteMeta(f.Title), "options": "i"}}
+	}
+
+	deleted := bson.M{"$exists": f.Trashed}
+	if f.DeletedBefore != nil {
+		deleted["$lt"] = *f.DeletedBefore
+	}
+	filter["deletedAt"] = deleted
+
+	return filter
+}
+
+// matches evaluates the filter in code, the same way mongo evaluates the result of bson
+func (f *TodoItemFilter) matches(item *TodoItemDb) bool {
+	if len(f.Ids) > 0 && !slices.Contains(f.Ids, item.Id) {
+		return false
+	}
+
+	if len(f.ParentIds) > 0 && (item.ParentId == nil || !slices.Contains(f.ParentIds, *item.ParentId)) {
+		return false
+	}
+
+	if len(f.ListIds) > 0 && (item.ListId == nil || !slices.Contains(f.ListIds, *item.ListId)) {
+		return false
+	}
+	if len(f.ExcludeListIds) > 0 && item.ListId != nil && slices.Contains(f.ExcludeListIds, *item.ListId) {
+		return false
+	}
+
+	if len(f.BlockedBy) > 0 && !slices.ContainsFunc(f.BlockedBy, func(id primitive.ObjectID) bool { return slices.Contains(item.BlockedBy, id) }) {
+		return false
+	}
+
+	if f.Completed != nil && item.Completed != *f.Completed {
+		return false
+	}
+
+	if f.DueBefore != nil && !item.DueDate.Before(*f.DueBefore) {
+		return false
+	}
+	if f.DueAfter != nil && !item.DueDate.After(*f.DueAfter) {
+		return false
+	}
+
+	if len(f.Labels) > 0 {
+		contains := func(label string) bool { return slices.Contains(item.Labels, label) }
+		if f.MatchAllLabels && !all(f.Labels, contains) {
+			return false
+		}
+		if !f.MatchAllLabels && !slices.ContainsFunc(f.Labels, contains) {
+			return false
+		}
+	}
+
+	if f.Title != "" && !strings.Contains(strings.ToLower(item.Title), strings.ToLower(f.Title)) {
+		return false
+	}
+
+	if (item.DeletedAt != nil) != f.Trashed {
+		return false
+	}
+	if f.DeletedBefore != nil && (item.DeletedAt == nil || !item.DeletedAt.Before(*f.DeletedBefore)) {
+		return false
+	}
+
+	return true
+}
+
+func all[T any](values []T, predicate func(T) bool) bool {
+	for _, v := range values {
+		if !predicate(v) {
+			return false
+		}
+	}
+
+	return true
+}
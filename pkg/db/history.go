@@ -0,0 +1,334 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrRevisionNotFound is returned when restoring a revision that doesn't exist
+var ErrRevisionNotFound = errors.New("revision not found")
+
+type actorKey struct{}
+
+// WithActor stores who is making the changes, it ends up in the revisions recorded by TodoItemHistory
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor stored by WithActor, or an empty string
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// TodoItemHistoryInterface gives access to the revisions of items
+type TodoItemHistoryInterface interface {
+	FindRevisions(context.Context, primitive.ObjectID) ([]TodoItemRevision, error)
+	// FindRevision returns a single revision of the item, or nil when it doesn't exist
+	FindRevision(ctx context.Context, id primitive.ObjectID, revision int64) (*TodoItemRevision, error)
+	// Restore rolls the item back to the given revision, taking it out of the trash or recreating it when it was deleted
+	Restore(ctx context.Context, id primitive.ObjectID, revision int64, version int64) (*TodoItemDb, error)
+}
+
+// TodoItemHistory wraps a TodoItemDbHandlerInterface and records a revision for every change made through it
+type TodoItemHistory struct {
+	TodoItemDbHandlerInterface
+	Revisions RevisionDbHandlerInterface
+}
+
+func (h *TodoItemHistory) InsertOne(context context.Context, new *TodoItemDb) (primitive.ObjectID, error) {
+	id, err := h.TodoItemDbHandlerInterface.InsertOne(context, new)
+	if err != nil {
+		return id, err
+	}
+
+	return id, h.recordCurrent(context, id, RevisionActionCreate)
+}
+
+func (h *TodoItemHistory) AddLabels(context context.Context, id primitive.ObjectID, labels ...string) (*TodoItemDb, error) {
+	item, err := h.TodoItemDbHandlerInterface.AddLabels(context, id, labels...)
+	return item, h.recordUpdate(context, item, err)
+}
+
+func (h *TodoItemHistory) AddLabelsToMany(context context.Context, ids []primitive.ObjectID, labels ...string) error {
+	if err := h.TodoItemDbHandlerInterface.AddLabelsToMany(context, ids, labels...); err != nil {
+		return err
+	}
+
+	return h.recordMany(context, ids)
+}
+
+func (h *TodoItemHistory) MoveToList(context context.Context, ids []primitive.ObjectID, listId primitive.ObjectID) error {
+	if err := h.TodoItemDbHandlerInterface.MoveToList(context, ids, listId); err != nil {
+		return err
+	}
+
+	return h.recordMany(context, ids)
+}
+
+func (h *TodoItemHistory) RemoveLabel(context context.Context, id primitive.ObjectID, label string) (*TodoItemDb, error) {
+	item, err := h.TodoItemDbHandlerInterface.RemoveLabel(context, id, label)
+	return item, h.recordUpdate(context, item, err)
+}
+
+func (h *TodoItemHistory) UpdateOneById(context context.Context, id primitive.ObjectID, update *TodoItemDb, version int64) (*TodoItemDb, error) {
+	item, err := h.TodoItemDbHandlerInterface.UpdateOneById(context, id, update, version)
+	return item, h.recordUpdate(context, item, err)
+}
+
+func (h *TodoItemHistory) PatchOneById(context context.Context, id primitive.ObjectID, update *TodoItemUpdate, version int64) (*TodoItemDb, error) {
+	item, err := h.TodoItemDbHandlerInterface.PatchOneById(context, id, update, version)
+	return item, h.recordUpdate(context, item, err)
+}
+
+func (h *TodoItemHistory) TrashOneById(context context.Context, id primitive.ObjectID, version int64) (*TodoItemDb, error) {
+	item, err := h.TodoItemDbHandlerInterface.TrashOneById(context, id, version)
+	if err != nil || item == nil {
+		return item, err
+	}
+
+	return item, h.record(context, RevisionActionTrash, item, nil)
+}
+
+func (h *TodoItemHistory) UntrashOneById(context context.Context, id primitive.ObjectID, version int64) (*TodoItemDb, error) {
+	item, err := h.TodoItemDbHandlerInterface.UntrashOneById(context, id, version)
+	if err != nil || item == nil {
+		return item, err
+	}
+
+	return item, h.record(context, RevisionActionUntrash, item, nil)
+}
+
+// DeleteOneById records the last state of the item as revision after its last version
+func (h *TodoItemHistory) DeleteOneById(context context.Context, id primitive.ObjectID, version int64) error {
+	item, err := h.TodoItemDbHandlerInterface.FindOneById(context, id)
+	if err != nil {
+		return err
+	}
+
+	if err := h.TodoItemDbHandlerInterface.DeleteOneById(context, id, version); err != nil || item == nil {
+		return err
+	}
+
+	item.Version++
+	return h.record(context, RevisionActionDelete, item, nil)
+}
+
+// FindRevisions returns the revisions of the item when the user of the context has a role on it
+func (h *TodoItemHistory) FindRevisions(context context.Context, id primitive.ObjectID) ([]TodoItemRevision, error) {
+	revisions, err := h.Revisions.FindRevisions(context, id)
+	if err != nil || len(revisions) == 0 {
+		return revisions, err
+	}
+
+	accessible, err := h.accessible(context, id, &revisions[0].Item)
+	if err != nil {
+		return nil, err
+	}
+
+	if !accessible {
+		return []TodoItemRevision{}, nil
+	}
+	return revisions, nil
+}
+
+func (h *TodoItemHistory) FindRevision(context context.Context, id primitive.ObjectID, revision int64) (*TodoItemRevision, error) {
+	source, err := h.Revisions.FindRevision(context, id, revision)
+	if err != nil || source == nil {
+		return nil, err
+	}
+
+	accessible, err := h.accessible(context, id, &source.Item)
+	if err != nil || !accessible {
+		return nil, err
+	}
+	return source, nil
+}
+
+func (h *TodoItemHistory) Restore(context context.Context, id primitive.ObjectID, revision int64, version int64) (*TodoItemDb, error) {
+	source, err := h.FindRevision(context, id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	if source == nil {
+		return nil, ErrRevisionNotFound
+	}
+
+	item, err := h.TodoItemDbHandlerInterface.UpdateOneById(context, id, &source.Item, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if item == nil {
+		item, err = h.restoreDeleted(context, source, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return item, h.record(context, RevisionActionRestore, item, &revision)
+}
+
+// restoreDeleted brings back an item that is in the trash or doesn't exist anymore, in the state of the source revision
+func (h *TodoItemHistory) restoreDeleted(context context.Context, source *TodoItemRevision, version int64) (*TodoItemDb, error) {
+	untrashed, err := h.UntrashOneById(context, source.ItemId, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if untrashed == nil {
+		return h.recreate(context, source, version)
+	}
+
+	return h.TodoItemDbHandlerInterface.UpdateOneById(context, source.ItemId, &source.Item, untrashed.Version)
+}
+
+// recreate inserts a deleted item again, continuing its version after the last revision
+func (h *TodoItemHistory) recreate(context context.Context, source *TodoItemRevision, version int64) (*TodoItemDb, error) {
+	// a deleted item can't be in the version the caller expects
+	if version != AnyVersion {
+		return nil, ErrVersionConflict
+	}
+
+	revisions, err := h.Revisions.FindRevisions(context, source.ItemId)
+	if err != nil {
+		return nil, err
+	}
+
+	item := copyTodoItem(source.Item)
+	item.Id = source.ItemId
+	item.DeletedAt = nil
+	item.Version = revisions[len(revisions)-1].Revision + 1
+	if _, err := h.TodoItemDbHandlerInterface.InsertOne(context, &item); err != nil {
+		return nil, err
+	}
+
+	return h.TodoItemDbHandlerInterface.FindOneById(context, item.Id)
+}
+
+// accessible tells whether the user of the context has a role on the item, or owned it when it doesn't exist anymore
+func (h *TodoItemHistory) accessible(context context.Context, id primitive.ObjectID, revision *TodoItemDb) (bool, error) {
+	item, err := h.TodoItemDbHandlerInterface.FindOneById(context, id)
+	if err != nil || item != nil {
+		return item != nil, err
+	}
+
+	// the owner of an item never changes, so every revision has the same one
+	return owns(context, revision.Owner), nil
+}
+
+// recordUpdate records the result of an update, unless it failed or the item doesn't exist
+func (h *TodoItemHistory) recordUpdate(context context.Context, item *TodoItemDb, err error) error {
+	if err != nil || item == nil {
+		return err
+	}
+
+	return h.record(context, RevisionActionUpdate, item, nil)
+}
+
+// recordMany records the current state of the items after a bulk update
+func (h *TodoItemHistory) recordMany(context context.Context, ids []primitive.ObjectID) error {
+	// the filter doesn't filter on ids when there are none
+	if len(ids) == 0 {
+		return nil
+	}
+
+	page, err := h.TodoItemDbHandlerInterface.FindAll(context, TodoItemFilter{Ids: ids}, PageOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, item := range page.Items {
+		if err := h.record(context, RevisionActionUpdate, &item, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordCurrent reads the item as it is stored and records it
+func (h *TodoItemHistory) recordCurrent(context context.Context, id primitive.ObjectID, action string) error {
+	item, err := h.TodoItemDbHandlerInterface.FindOneById(context, id)
+	if err != nil || item == nil {
+		return err
+	}
+
+	return h.record(context, action, item, nil)
+}
+
+// record stores the item as the revision of its version, with the changes compared to the previous revision
+func (h *TodoItemHistory) record(context context.Context, action string, item *TodoItemDb, restoredFrom *int64) error {
+	changes := []FieldChange{}
+	if action != RevisionActionDelete {
+		previous, err := h.Revisions.FindRevision(context, item.Id, item.Version-1)
+		if err != nil {
+			return err
+		}
+
+		if previous != nil {
+			changes = diffTodoItems(&previous.Item, item)
+		} else {
+			changes = diffTodoItems(nil, item)
+		}
+	}
+
+	return h.Revisions.InsertRevision(context, &TodoItemRevision{
+		ItemId:       item.Id,
+		Revision:     item.Version,
+		Action:       action,
+		Actor:        ActorFrom(context),
+		Time:         time.Now().UTC(),
+		Changes:      changes,
+		RestoredFrom: restoredFrom,
+		Item:         *item,
+	})
+}
+
+// diffTodoItems lists the changed fields, without a previous state every field that is set counts as changed
+func diffTodoItems(from, to *TodoItemDb) []FieldChange {
+	unknown := from == nil
+	if unknown {
+		from = &TodoItemDb{}
+	}
+
+	changes := []FieldChange{}
+	add := func(field string, changed bool, fromValue, toValue any) {
+		if unknown {
+			fromValue = nil
+		}
+		if changed {
+			changes = append(changes, FieldChange{Field: field, From: fromValue, To: toValue})
+		}
+	}
+
+	add("title", from.Title != to.Title, from.Title, to.Title)
+	add("dueDate", !from.DueDate.Equal(to.DueDate), from.DueDate, to.DueDate)
+	add("labels", !slices.Equal(from.Labels, to.Labels), from.Labels, to.Labels)
+	add("description", from.Description != to.Description, from.Description, to.Description)
+	add("completed", from.Completed != to.Completed, from.Completed, to.Completed)
+	add("recurrence", from.Recurrence != to.Recurrence, from.Recurrence, to.Recurrence)
+	add("blockedBy", !slices.Equal(from.BlockedBy, to.BlockedBy), from.BlockedBy, to.BlockedBy)
+	add("listId", !equalIds(from.ListId, to.ListId), from.ListId, to.ListId)
+	add("parentId", !equalIds(from.ParentId, to.ParentId), from.ParentId, to.ParentId)
+	add("deletedAt", !equalTimes(from.DeletedAt, to.DeletedAt), from.DeletedAt, to.DeletedAt)
+	return changes
+}
+
+func equalIds(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
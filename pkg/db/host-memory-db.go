@@ -1,7 +1,7 @@
 package db
 
 import (
-	"fmt"
+	"log/slog"
 
 	"github.com/tryvium-travels/memongo"
 )
@@ -23,7 +23,6 @@ func (mm *MockMongo) HostMemoryDb(mongodPath string) (string, error) {
 		server.Stop()
 	}
 
-	// Print the MongoDB server URI.
-	fmt.Printf("Mongo deployed at: %s\n", server.URI())
+	slog.Info("started memory mongo", "uri", server.URI())
 	return server.URI(), nil
 }
@@ -0,0 +1,231 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// TodoItemMetrics wraps a TodoItemDbHandlerInterface and records the duration and the errors of every call, by method
+type TodoItemMetrics struct {
+	TodoItemDbHandlerInterface
+	durations *prometheus.HistogramVec
+	errors    *prometheus.CounterVec
+}
+
+// NewTodoItemMetrics registers the metrics of the handler, including the number of items by state which is counted at most once per refresh
+func NewTodoItemMetrics(registerer prometheus.Registerer, handler TodoItemDbHandlerInterface, refresh time.Duration) *TodoItemMetrics {
+	factory := promauto.With(registerer)
+	m := &TodoItemMetrics{
+		TodoItemDbHandlerInterface: handler,
+		durations: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "todo_db_operation_duration_seconds",
+			Help:    "Duration of the todo item storage operations.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		errors: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: "todo_db_operation_errors_total",
+			Help: "Todo item storage operations that failed.",
+		}, []string{"operation"}),
+	}
+
+	registerer.MustRegister(&itemCounts{
+		handler: handler,
+		refresh: refresh,
+		desc:    prometheus.NewDesc("todo_items", "Todo items by state, items in the trash are only counted as trashed.", []string{"state"}, nil),
+	})
+	return m
+}
+
+// itemCounts collects the number of items by state, it keeps the counts for the refresh so scrapes don't count every item each time
+type itemCounts struct {
+	handler TodoItemDbHandlerInterface
+	refresh time.Duration
+	desc    *prometheus.Desc
+
+	mu        sync.Mutex
+	countedAt time.Time
+	counts    map[string]int64
+}
+
+func (i *itemCounts) Describe(ch chan<- *prometheus.Desc) {
+	ch <- i.desc
+}
+
+// Collect sends the counts, counting again when they are older than the refresh. When counting fails the previous counts are sent.
+func (i *itemCounts) Collect(ch chan<- prometheus.Metric) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.counts == nil || time.Since(i.countedAt) >= i.refresh {
+		// the context isn't scoped to a user, so every item is counted
+		ctx := context.Background()
+		if counts, err := i.count(ctx); err != nil {
+			slog.ErrorContext(ctx, "failed to count the items for the metrics", "error", err)
+		} else {
+			i.counts, i.countedAt = counts, time.Now()
+		}
+	}
+
+	for state, count := range i.counts {
+		ch <- prometheus.MustNewConstMetric(i.desc, prometheus.GaugeValue, float64(count), state)
+	}
+}
+
+func (i *itemCounts) count(context context.Context) (map[string]int64, error) {
+	completed, open := true, false
+	states := map[string]TodoItemFilter{
+		"open":      {Completed: &open},
+		"completed": {Completed: &completed},
+		"trashed":   {Trashed: true},
+	}
+
+	counts := map[string]int64{}
+	for state, filter := range states {
+		count, err := i.handler.Count(context, filter)
+		if err != nil {
+			return nil, err
+		}
+		counts[state] = count
+	}
+	return counts, nil
+}
+
+// observe records a call of the operation that started at start
+func (m *TodoItemMetrics) observe(operation string, start time.Time, err error) {
+	m.durations.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		m.errors.WithLabelValues(operation).Inc()
+	}
+}
+
+func (m *TodoItemMetrics) InsertOne(context context.Context, new *TodoItemDb) (id primitive.ObjectID, err error) {
+	defer func(start time.Time) { m.observe("InsertOne", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.InsertOne(context, new)
+}
+
+func (m *TodoItemMetrics) FindOneById(context context.Context, id primitive.ObjectID) (item *TodoItemDb, err error) {
+	defer func(start time.Time) { m.observe("FindOneById", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.FindOneById(context, id)
+}
+
+func (m *TodoItemMetrics) FindAll(context context.Context, filter TodoItemFilter, page PageOptions) (items *TodoItemPage, err error) {
+	defer func(start time.Time) { m.observe("FindAll", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.FindAll(context, filter, page)
+}
+
+func (m *TodoItemMetrics) Count(context context.Context, filter TodoItemFilter) (count int64, err error) {
+	defer func(start time.Time) { m.observe("Count", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.Count(context, filter)
+}
+
+func (m *TodoItemMetrics) AddLabels(context context.Context, id primitive.ObjectID, labels ...string) (item *TodoItemDb, err error) {
+	defer func(start time.Time) { m.observe("AddLabels", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.AddLabels(context, id, labels...)
+}
+
+func (m *TodoItemMetrics) AddLabelsToMany(context context.Context, ids []primitive.ObjectID, labels ...string) (err error) {
+	defer func(start time.Time) { m.observe("AddLabelsToMany", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.AddLabelsToMany(context, ids, labels...)
+}
+
+func (m *TodoItemMetrics) MoveToList(context context.Context, ids []primitive.ObjectID, listId primitive.ObjectID) (err error) {
+	defer func(start time.Time) { m.observe("MoveToList", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.MoveToList(context, ids, listId)
+}
+
+func (m *TodoItemMetrics) RemoveLabel(context context.Context, id primitive.ObjectID, label string) (item *TodoItemDb, err error) {
+	defer func(start time.Time) { m.observe("RemoveLabel", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.RemoveLabel(context, id, label)
+}
+
+func (m *TodoItemMetrics) TrashOneById(context context.Context, id primitive.ObjectID, version int64) (item *TodoItemDb, err error) {
+	defer func(start time.Time) { m.observe("TrashOneById", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.TrashOneById(context, id, version)
+}
+
+func (m *TodoItemMetrics) UntrashOneById(context context.Context, id primitive.ObjectID, version int64) (item *TodoItemDb, err error) {
+	defer func(start time.Time) { m.observe("UntrashOneById", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.UntrashOneById(context, id, version)
+}
+
+func (m *TodoItemMetrics) DeleteOneById(context context.Context, id primitive.ObjectID, version int64) (err error) {
+	defer func(start time.Time) { m.observe("DeleteOneById", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.DeleteOneById(context, id, version)
+}
+
+func (m *TodoItemMetrics) UpdateOneById(context context.Context, id primitive.ObjectID, update *TodoItemDb, version int64) (item *TodoItemDb, err error) {
+	defer func(start time.Time) { m.observe("UpdateOneById", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.UpdateOneById(context, id, update, version)
+}
+
+func (m *TodoItemMetrics) PatchOneById(context context.Context, id primitive.ObjectID, update *TodoItemUpdate, version int64) (item *TodoItemDb, err error) {
+	defer func(start time.Time) { m.observe("PatchOneById", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.PatchOneById(context, id, update, version)
+}
+
+func (m *TodoItemMetrics) SetShare(context context.Context, id primitive.ObjectID, share Share) (item *TodoItemDb, err error) {
+	defer func(start time.Time) { m.observe("SetShare", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.SetShare(context, id, share)
+}
+
+func (m *TodoItemMetrics) RemoveShare(context context.Context, id primitive.ObjectID, user primitive.ObjectID) (item *TodoItemDb, err error) {
+	defer func(start time.Time) { m.observe("RemoveShare", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.RemoveShare(context, id, user)
+}
+
+func (m *TodoItemMetrics) CountChildren(context context.Context, ids []primitive.ObjectID) (counts map[primitive.ObjectID]ChildCount, err error) {
+	defer func(start time.Time) { m.observe("CountChildren", start, err) }(time.Now())
+	return m.TodoItemDbHandlerInterface.CountChildren(context, ids)
+}
+
+// PoolMetrics returns a pool monitor for Connection that keeps the connection pool metrics of the mongo driver up to date
+func PoolMetrics(registerer prometheus.Registerer) *event.PoolMonitor {
+	factory := promauto.With(registerer)
+	open := factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mongo_pool_connections",
+		Help: "Connections the pool has open.",
+	}, []string{"address"})
+	inUse := factory.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "mongo_pool_connections_in_use",
+		Help: "Connections checked out of the pool.",
+	}, []string{"address"})
+	checkouts := factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_pool_checkouts_total",
+		Help: "Connections checked out of the pool.",
+	}, []string{"address"})
+	failures := factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_pool_checkout_failures_total",
+		Help: "Connections that couldn't be checked out of the pool, by reason.",
+	}, []string{"address", "reason"})
+	cleared := factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "mongo_pool_cleared_total",
+		Help: "Times the pool was cleared after an error.",
+	}, []string{"address"})
+
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			switch e.Type {
+			case event.ConnectionCreated:
+				open.WithLabelValues(e.Address).Inc()
+			case event.ConnectionClosed:
+				open.WithLabelValues(e.Address).Dec()
+			case event.GetSucceeded:
+				inUse.WithLabelValues(e.Address).Inc()
+				checkouts.WithLabelValues(e.Address).Inc()
+			case event.ConnectionReturned:
+				inUse.WithLabelValues(e.Address).Dec()
+			case event.GetFailed:
+				failures.WithLabelValues(e.Address, e.Reason).Inc()
+			case event.PoolCleared:
+				cleared.WithLabelValues(e.Address).Inc()
+			}
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrape returns the metrics of the registry in the text format
+func scrape(registry *prometheus.Registry) string {
+	w := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return w.Body.String()
+}
+
+func TestTodoItemMetrics(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	t.Run("Records the operations and counts the items", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		h := NewTodoItemMetrics(registry, &TodoItemMemoryDbHandler{}, 0)
+		open, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Open"})
+		h.InsertOne(ctx, &TodoItemDb{Title: "Done", Completed: true})
+		// a stale version fails with a conflict
+		h.DeleteOneById(ctx, open, 42)
+
+		out := scrape(registry)
+		for _, want := range []string{
+			`todo_db_operation_duration_seconds_count{operation="InsertOne"} 2`,
+			`todo_db_operation_errors_total{operation="DeleteOneById"} 1`,
+			`todo_items{state="open"} 1`,
+			`todo_items{state="completed"} 1`,
+			`todo_items{state="trashed"} 0`,
+		} {
+			if !strings.Contains(out, want+"\n") {
+				t.Errorf("GET /metrics = %s, want a line %s", out, want)
+			}
+		}
+	})
+
+	t.Run("Keeps the item counts until the refresh", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		h := NewTodoItemMetrics(registry, &TodoItemMemoryDbHandler{}, time.Hour)
+		h.InsertOne(ctx, &TodoItemDb{Title: "Open"})
+		scrape(registry)
+		h.InsertOne(ctx, &TodoItemDb{Title: "Other"})
+
+		if out := scrape(registry); !strings.Contains(out, `todo_items{state="open"} 1`+"\n") {
+			t.Errorf("GET /metrics = %s, want the counts of the first scrape", out)
+		}
+	})
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultMigrationsCollection is the collection the applied migrations are recorded in when Migrator.Collection is empty
+const DefaultMigrationsCollection = "migrations"
+
+// ErrMigrationInProgress is returned when another run claimed a migration without finishing it, either because it is
+// still applying it or because it stopped halfway. In the latter case the record of the migration has to be removed.
+var ErrMigrationInProgress = errors.New("the migration is in progress")
+
+// Migration moves the stored documents from one schema version to the next. Pending counts the documents Apply would change,
+// without changing them. Apply returns the number of documents it changed and has to be idempotent, so it can be retried.
+type Migration struct {
+	Version     int
+	Description string
+	Pending     func(context.Context, *mongo.Database) (int64, error)
+	Apply       func(context.Context, *mongo.Database) (int64, error)
+}
+
+// MigrationResult is what a run did with a single migration
+type MigrationResult struct {
+	Version     int
+	Description string
+	// Applied is true when the migration had been applied before the run
+	Applied bool
+	// Documents is the number of documents the run changed, or would change in a dry run
+	Documents int64
+}
+
+// migrationRecord claims a migration while it is applied, AppliedAt is set once it succeeded
+type migrationRecord struct {
+	Version     int        `bson:"_id"`
+	Description string     `bson:"description"`
+	StartedAt   time.Time  `bson:"startedAt"`
+	AppliedAt   *time.Time `bson:"appliedAt,omitempty"`
+	Documents   int64      `bson:"documents"`
+}
+
+// Migrator applies the migrations the database is missing in the order of their versions.
+// Each migration is claimed by inserting its record, so replicas starting at the same time never apply it twice.
+type Migrator struct {
+	Database   *mongo.Database
+	Migrations []Migration
+	// Collection is the name of the collection the applied migrations are recorded in, DefaultMigrationsCollection when empty
+	Collection string
+}
+
+// Run applies the missing migrations, it stops at the first one that fails. A dry run only counts the documents
+// the missing migrations would change.
+func (m *Migrator) Run(context context.Context, dryRun bool) ([]MigrationResult, error) {
+	migrations := slices.Clone(m.Migrations)
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+
+	coll := m.Database.Collection(orDefault(m.Collection, DefaultMigrationsCollection))
+	cur, err := coll.Find(context, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []migrationRecord
+	if err := cur.All(context, &records); err != nil {
+		return nil, err
+	}
+	applied := map[int]migrationRecord{}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	results := []MigrationResult{}
+	for _, migration := range migrations {
+		result := MigrationResult{Version: migration.Version, Description: migration.Description}
+		if record, ok := applied[migration.Version]; ok {
+			if record.AppliedAt == nil {
+				return results, fmt.Errorf("migration %d started at %v: %w", migration.Version, record.StartedAt, ErrMigrationInProgress)
+			}
+			result.Applied = true
+			results = append(results, result)
+			continue
+		}
+
+		if dryRun {
+			result.Documents, err = migration.Pending(context, m.Database)
+			if err != nil {
+				return results, fmt.Errorf("migration %d: %w", migration.Version, err)
+			}
+			results = append(results, result)
+			continue
+		}
+
+		result.Documents, err = m.apply(context, coll, migration)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// RunWaiting applies the missing migrations like Run, but waits for the ones another run is applying: it polls every
+// interval until they are applied. It fails with ErrMigrationInProgress once a claim is older than stale, as the run
+// that made it most likely stopped halfway.
+func (m *Migrator) RunWaiting(context context.Context, interval, stale time.Duration) ([]MigrationResult, error) {
+	for {
+		results, err := m.Run(context, false)
+		if !errors.Is(err, ErrMigrationInProgress) {
+			return results, err
+		}
+
+		isStale, staleErr := m.staleClaim(context, stale)
+		if staleErr != nil {
+			return results, errors.Join(err, staleErr)
+		}
+		if isStale {
+			return results, err
+		}
+
+		slog.InfoContext(context, "waiting for a migration in progress", "error", err)
+		select {
+		case <-context.Done():
+			return results, errors.Join(err, context.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// staleClaim tells whether one of the migrations was claimed more than stale ago without being applied
+func (m *Migrator) staleClaim(context context.Context, stale time.Duration) (bool, error) {
+	versions := []int{}
+	for _, migration := range m.Migrations {
+		versions = append(versions, migration.Version)
+	}
+
+	coll := m.Database.Collection(orDefault(m.Collection, DefaultMigrationsCollection))
+	count, err := coll.CountDocuments(context, bson.M{
+		"_id":       bson.M{"$in": versions},
+		"appliedAt": bson.M{"$exists": false},
+		"startedAt": bson.M{"$lt": time.Now().UTC().Add(-stale)},
+	})
+	return count > 0, err
+}
+
+// apply claims the migration, applies it and records its success. The claim is released when the migration fails.
+func (m *Migrator) apply(context context.Context, coll *mongo.Collection, migration Migration) (int64, error) {
+	record := migrationRecord{Version: migration.Version, Description: migration.Description, StartedAt: time.Now().UTC()}
+	_, err := coll.InsertOne(context, record)
+	if mongo.IsDuplicateKeyError(err) {
+		// another replica claimed it after the records were read
+		return 0, fmt.Errorf("migration %d: %w", migration.Version, ErrMigrationInProgress)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	slog.InfoContext(context, "applying migration", "version", migration.Version, "description", migration.Description)
+	documents, err := migration.Apply(context, m.Database)
+	if err != nil {
+		if _, deleteErr := coll.DeleteOne(context, bson.M{"_id": migration.Version}); deleteErr != nil {
+			err = errors.Join(err, deleteErr)
+		}
+		return 0, fmt.Errorf("migration %d: %w", migration.Version, err)
+	}
+
+	appliedAt := time.Now().UTC()
+	_, err = coll.UpdateOne(context, bson.M{"_id": migration.Version}, bson.M{"$set": bson.M{"appliedAt": appliedAt, "documents": documents}})
+	if err != nil {
+		return 0, err
+	}
+	slog.InfoContext(context, "applied migration", "version", migration.Version, "documents", documents, "duration", appliedAt.Sub(record.StartedAt))
+	return documents, nil
+}
+
+// Migrations are the migrations of the items and revisions stored in the given collections, the default ones when they are empty
+func Migrations(items, revisions string) []Migration {
+	items = orDefault(items, DefaultItemsCollection)
+	revisions = orDefault(revisions, DefaultRevisionsCollection)
+
+	return []Migration{
+		{
+			// string is kept until every instance reads title, a later migration removes it
+			Version:     1,
+			Description: "copy the title of items from string to title",
+			Pending: func(context context.Context, database *mongo.Database) (int64, error) {
+				itemCount, err := database.Collection(items).CountDocuments(context, fieldDiffers("string", "title"))
+				if err != nil {
+					return 0, err
+				}
+				revisionCount, err := database.Collection(revisions).CountDocuments(context, fieldDiffers("item.string", "item.title"))
+				return itemCount + revisionCount, err
+			},
+			Apply: func(context context.Context, database *mongo.Database) (int64, error) {
+				itemCount, err := copyField(context, database.Collection(items), "string", "title")
+				if err != nil {
+					return 0, err
+				}
+				revisionCount, err := copyField(context, database.Collection(revisions), "item.string", "item.title")
+				return itemCount + revisionCount, err
+			},
+		},
+	}
+}
+
+// fieldDiffers matches the documents that have the field from with another value than the field to
+func fieldDiffers(from, to string) bson.M {
+	return bson.M{from: bson.M{"$exists": true}, "$expr": bson.M{"$ne": bson.A{"$" + from, "$" + to}}}
+}
+
+// copyField copies the field from to the field to of every document where they differ, from is left as it is
+func copyField(context context.Context, coll *mongo.Collection, from, to string) (int64, error) {
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{to: "$" + from}}}}
+	result, err := coll.UpdateMany(context, fieldDiffers(from, to), update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestMigrator_Run(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	database, close := createDb(t)
+	defer close()
+
+	items := database.Collection(DefaultItemsCollection)
+	revisions := database.Collection(DefaultRevisionsCollection)
+	items.InsertMany(ctx, []any{
+		bson.M{"string": "Legacy_Title"},
+		bson.M{"title": "Test_Title"},
+		bson.M{"title": "Old_Title", "string": "New_Title"},
+	})
+	revisions.InsertOne(ctx, bson.M{"revision": 1, "item": bson.M{"string": "Legacy_Title"}})
+
+	migrator := &Migrator{Database: database, Migrations: Migrations("", "")}
+
+	t.Run("A dry run counts the documents without changing them", func(t *testing.T) {
+		results, err := migrator.Run(ctx, true)
+		if err != nil {
+			t.Fatalf("Migrator.Run() error = %v, wantErr %v", err, false)
+		}
+		if len(results) != 1 || results[0].Applied || results[0].Documents != 3 {
+			t.Errorf("Migrator.Run() = %+v, want 3 pending documents", results)
+		}
+		if count, _ := items.CountDocuments(ctx, bson.M{"title": bson.M{"$exists": true}}); count != 2 {
+			t.Errorf("Migrator.Run() changed %d documents in a dry run", count-2)
+		}
+	})
+
+	t.Run("Copies the title field", func(t *testing.T) {
+		results, err := migrator.Run(ctx, false)
+		if err != nil {
+			t.Fatalf("Migrator.Run() error = %v, wantErr %v", err, false)
+		}
+		if len(results) != 1 || results[0].Applied || results[0].Documents != 3 {
+			t.Errorf("Migrator.Run() = %+v, want 3 changed documents", results)
+		}
+
+		var docs []bson.M
+		cur, _ := items.Find(ctx, bson.M{})
+		cur.All(ctx, &docs)
+		// the legacy field is kept for the instances that still read it
+		for _, doc := range docs {
+			if legacy, ok := doc["string"]; doc["title"] == nil || (ok && doc["title"] != legacy) {
+				t.Errorf("Migrator.Run() left %v", doc)
+			}
+		}
+
+		var revision TodoItemRevision
+		revisions.FindOne(ctx, bson.M{"item.title": "Legacy_Title"}).Decode(&revision)
+		if revision.Item.Title != "Legacy_Title" {
+			t.Errorf("Migrator.Run() didn't migrate the revision")
+		}
+	})
+
+	t.Run("Skips applied migrations", func(t *testing.T) {
+		results, err := migrator.Run(ctx, false)
+		if err != nil {
+			t.Fatalf("Migrator.Run() error = %v, wantErr %v", err, false)
+		}
+		if len(results) != 1 || !results[0].Applied {
+			t.Errorf("Migrator.Run() = %+v, want the migration to be applied before", results)
+		}
+	})
+
+	t.Run("Releases the claim of a failed migration", func(t *testing.T) {
+		failing := &Migrator{Database: database, Migrations: []Migration{{
+			Version: 2,
+			Apply:   func(context.Context, *mongo.Database) (int64, error) { return 0, errors.New("failed") },
+		}}}
+		if _, err := failing.Run(ctx, false); err == nil {
+			t.Fatalf("Migrator.Run() error = %v, wantErr %v", err, true)
+		}
+		if count, _ := database.Collection(DefaultMigrationsCollection).CountDocuments(ctx, bson.M{"_id": 2}); count != 0 {
+			t.Errorf("Migrator.Run() kept the claim of the failed migration")
+		}
+	})
+
+	t.Run("Stops at migrations that are in progress", func(t *testing.T) {
+		database.Collection(DefaultMigrationsCollection).InsertOne(ctx, bson.M{"_id": 3})
+		pending := &Migrator{Database: database, Migrations: []Migration{{Version: 3}}}
+		if _, err := pending.Run(ctx, false); !errors.Is(err, ErrMigrationInProgress) {
+			t.Errorf("Migrator.Run() error = %v, want %v", err, ErrMigrationInProgress)
+		}
+	})
+
+	t.Run("Waits for migrations that are in progress", func(t *testing.T) {
+		migrations := database.Collection(DefaultMigrationsCollection)
+		migrations.InsertOne(ctx, bson.M{"_id": 4, "startedAt": time.Now().UTC()})
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			migrations.UpdateOne(ctx, bson.M{"_id": 4}, bson.M{"$set": bson.M{"appliedAt": time.Now().UTC()}})
+		}()
+
+		waiting := &Migrator{Database: database, Migrations: []Migration{{Version: 4}}}
+		results, err := waiting.RunWaiting(ctx, 10*time.Millisecond, time.Minute)
+		if err != nil {
+			t.Fatalf("Migrator.RunWaiting() error = %v, wantErr %v", err, false)
+		}
+		if len(results) != 1 || !results[0].Applied {
+			t.Errorf("Migrator.RunWaiting() = %+v, want the migration applied by the other run", results)
+		}
+	})
+
+	t.Run("Stops waiting for stale claims", func(t *testing.T) {
+		database.Collection(DefaultMigrationsCollection).InsertOne(ctx, bson.M{"_id": 5, "startedAt": time.Now().UTC().Add(-time.Hour)})
+		stale := &Migrator{Database: database, Migrations: []Migration{{Version: 5}}}
+		if _, err := stale.RunWaiting(ctx, 10*time.Millisecond, time.Minute); !errors.Is(err, ErrMigrationInProgress) {
+			t.Errorf("Migrator.RunWaiting() error = %v, want %v", err, ErrMigrationInProgress)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ownerKey struct{}
+
+// WithOwner scopes the handlers to the items and lists of the user: reads and writes only see what the user owns
+// or what is shared with them, and inserted items and lists are owned by the user. Contexts without an owner, like the ones of workers, see everything.
+func WithOwner(ctx context.Context, owner primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFrom returns the owner stored by WithOwner, ok is false when the context isn't scoped to a user
+func OwnerFrom(ctx context.Context) (owner primitive.ObjectID, ok bool) {
+	owner, ok = ctx.Value(ownerKey{}).(primitive.ObjectID)
+	return owner, ok
+}
+
+// ownedBy adds the condition on the owner field to a mongo filter, when the context is scoped to a user
+func ownedBy(ctx context.Context, filter bson.D) bson.D {
+	if owner, ok := OwnerFrom(ctx); ok {
+		return append(filter, bson.E{Key: "owner", Value: owner})
+	}
+	return filter
+}
+
+// owns tells whether the user of the context may see something owned by owner
+func owns(ctx context.Context, owner primitive.ObjectID) bool {
+	scoped, ok := OwnerFrom(ctx)
+	return !ok || scoped == owner
+}
+
+// ownerOf returns who owns what is inserted in the context, which is the user of the context when it is scoped
+func ownerOf(ctx context.Context, owner primitive.ObjectID) primitive.ObjectID {
+	if scoped, ok := OwnerFrom(ctx); ok {
+		return scoped
+	}
+	return owner
+}
@@ -0,0 +1,242 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the fields items can be sorted on, mapped to their bson names. The title is sorted on a field computed from titleExpr.
+var sortableFields = map[string]string{
+	"id":      "_id",
+	"title":   "sortTitle",
+	"dueDate": "dueDate",
+}
+
+// titleExpr is the title of an item the way UnmarshalBSON reads it, so items that only have
+// the former field of the title are filtered and sorted on it as well
+var titleExpr = bson.M{"$ifNull": bson.A{"$string", "$title"}}
+
+// SortField is a single key of a sort spec, Field is the json name of the field
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// PageOptions selects a page of items, ordered by Sort and then by id
+type PageOptions struct {
+	Sort []SortField
+	// After is the cursor of the last item of the previous page, nil starts at the beginning
+	After *PageCursor
+	// Limit is the max amount of items in the page, 0 means no limit
+	Limit int
+}
+
+// PageCursor holds the sort values of the last item or list of a page, so the next page can continue after it
+type PageCursor struct {
+	Sort    string             `bson:"s"`
+	Id      primitive.ObjectID `bson:"i"`
+	Title   string             `bson:"t"`
+	DueDate time.Time          `bson:"d"`
+	// Name is only set for lists
+	Name string `bson:"n,omitempty"`
+}
+
+// TodoItemPage is a single page of items, when Truncated is set the following page starts after Next
+type TodoItemPage struct {
+	Items     []TodoItemDb
+	Next      *PageCursor
+	Truncated bool
+}
+
+// ParseSort parses a comma separated sort spec like "dueDate,-title", a leading "-" sorts descending
+func ParseSort(spec string) ([]SortField, error) {
+	sort := []SortField{}
+	if spec == "" {
+		return sort, nil
+	}
+
+	seen := map[string]bool{}
+	for _, key := range strings.Split(spec, ",") {
+		field := SortField{Field: strings.TrimPrefix(key, "-"), Descending: strings.HasPrefix(key, "-")}
+		if _, ok := sortableFields[field.Field]; !ok {
+			return nil, fmt.Errorf("unknown sort field %q", field.Field)
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("duplicate sort field %q", field.Field)
+		}
+
+		seen[field.Field] = true
+		sort = append(sort, field)
+	}
+
+	return sort, nil
+}
+
+func formatSort(sort []SortField) string {
+	keys := make([]string, len(sort))
+	for i, s := range sort {
+		keys[i] = s.Field
+		if s.Descending {
+			keys[i] = "-" + s.Field
+		}
+	}
+
+	return strings.Join(keys, ",")
+}
+
+// sortKeys returns the sort with the id as final tie-breaker, which makes the order total
+func sortKeys(sort []SortField) []SortField {
+	for i, s := range sort {
+		if s.Field == "id" {
+			return sort[:i+1]
+		}
+	}
+
+	return append(sort[:len(sort):len(sort)], SortField{Field: "id"})
+}
+
+// Token encodes the cursor into an opaque string for clients
+func (c *PageCursor) Token() (string, error) {
+	b, err := bson.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ParsePageToken decodes a token created by PageCursor.Token, it has to be created with the same sort
+func ParsePageToken(token string, sort []SortField) (*PageCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+
+	cursor := &PageCursor{}
+	if err := bson.Unmarshal(b, cursor); err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+
+	if cursor.Sort != formatSort(sort) {
+		return nil, fmt.Errorf("page token was created with a different sort")
+	}
+
+	return cursor, nil
+}
+
+// ParseListPageToken decodes a token of a page of lists
+func ParseListPageToken(token string) (*PageCursor, error) {
+	return ParsePageToken(token, listSort)
+}
+
+func newPageCursor(item *TodoItemDb, sort []SortField) *PageCursor {
+	return &PageCursor{
+		Sort:    formatSort(sort),
+		Id:      item.Id,
+		Title:   item.Title,
+		DueDate: item.DueDate,
+	}
+}
+
+func (c *PageCursor) value(field string) any {
+	switch field {
+	case "title":
+		return c.Title
+	case "dueDate":
+		return c.DueDate
+	default:
+		return c.Id
+	}
+}
+
+// newTodoItemPage builds a page out of items that were fetched with one item more than the limit,
+// that extra item only tells whether there is a following page and is not returned
+func newTodoItemPage(items []TodoItemDb, page PageOptions) *TodoItemPage {
+	result := &TodoItemPage{Items: items}
+	if page.Limit > 0 && len(items) > page.Limit {
+		result.Items = items[:page.Limit]
+		result.Next = newPageCursor(&items[page.Limit-1], page.Sort)
+		result.Truncated = true
+	}
+
+	return result
+}
+
+// sortsByTitle tells whether the items are sorted on the computed title field
+func sortsByTitle(sort []SortField) bool {
+	return slices.ContainsFunc(sortKeys(sort), func(s SortField) bool { return s.Field == "title" })
+}
+
+// mongoSort converts the sort into a mongo sort document
+func mongoSort(sort []SortField) bson.D {
+	spec := bson.D{}
+	for _, s := range sortKeys(sort) {
+		direction := 1
+		if s.Descending {
+			direction = -1
+		}
+		spec = append(spec, bson.E{Key: sortableFields[s.Field], Value: direction})
+	}
+
+	return spec
+}
+
+// mongoKeyset matches the items that sort after the cursor, for the sort "a,-b" that is
+// a > after.a OR (a == after.a AND b < after.b) OR (a == after.a AND b == after.b AND _id > after._id)
+func mongoKeyset(sort []SortField, after *PageCursor) bson.M {
+	or := bson.A{}
+	equal := bson.M{}
+	for _, s := range sortKeys(sort) {
+		operator := "$gt"
+		if s.Descending {
+			operator = "$lt"
+		}
+
+		condition := bson.M{sortableFields[s.Field]: bson.M{operator: after.value(s.Field)}}
+		for key, value := range equal {
+			condition[key] = value
+		}
+
+		or = append(or, condition)
+		equal[sortableFields[s.Field]] = after.value(s.Field)
+	}
+
+	return bson.M{"$or": or}
+}
+
+// compareBySort orders two items the same way mongoSort orders them in mongo
+func compareBySort(a, b *TodoItemDb, sort []SortField) int {
+	for _, s := range sortKeys(sort) {
+		var c int
+		switch s.Field {
+		case "title":
+			c = strings.Compare(a.Title, b.Title)
+		case "dueDate":
+			c = a.DueDate.Compare(b.DueDate)
+		default:
+			c = bytes.Compare(a.Id[:], b.Id[:])
+		}
+
+		if s.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// precedes tells whether the cursor sorts before the item
+func (c *PageCursor) precedes(item *TodoItemDb, sort []SortField) bool {
+	last := TodoItemDb{Id: c.Id, Title: c.Title, DueDate: c.DueDate}
+	return compareBySort(item, &last, sort) > 0
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []SortField
+		wantErr bool
+	}{
+		{"", []SortField{}, false},
+		{"dueDate,-title", []SortField{{Field: "dueDate"}, {Field: "title", Descending: true}}, false},
+		{"-id", []SortField{{Field: "id", Descending: true}}, false},
+		{"description", nil, true},
+		{"title,-title", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSort(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseSort() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePageToken(t *testing.T) {
+	sort := []SortField{{Field: "title"}}
+	token, err := (&PageCursor{Sort: "title", Title: "Test_Title"}).Token()
+	if err != nil {
+		t.Fatalf("PageCursor.Token() error = %v, wantErr %v", err, false)
+	}
+
+	t.Run("Successfully parsed a token", func(t *testing.T) {
+		cursor, err := ParsePageToken(token, sort)
+		if err != nil || cursor.Title != "Test_Title" {
+			t.Errorf("ParsePageToken() = %v, %v, want title %q", cursor, err, "Test_Title")
+		}
+	})
+
+	t.Run("Rejects a token of another sort", func(t *testing.T) {
+		if _, err := ParsePageToken(token, []SortField{{Field: "dueDate"}}); err == nil {
+			t.Errorf("ParsePageToken() error = %v, wantErr %v", err, true)
+		}
+	})
+
+	t.Run("Rejects garbage", func(t *testing.T) {
+		if _, err := ParsePageToken("not-a-token", sort); err == nil {
+			t.Errorf("ParsePageToken() error = %v, wantErr %v", err, true)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitMemoryDbHandler is an in-process implementation of RateLimitDbHandlerInterface, every replica limits on its own.
+// The zero value is ready to use.
+type RateLimitMemoryDbHandler struct {
+	mu       sync.Mutex
+	buckets  map[string]rateLimitBucket
+	prunedAt time.Time
+}
+
+func (h *RateLimitMemoryDbHandler) Take(context context.Context, key string, limit RateLimit, now time.Time) (*RateLimitResult, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.buckets == nil {
+		h.buckets = map[string]rateLimitBucket{}
+	}
+	h.prune(now)
+
+	tokens := float64(limit.Limit)
+	if bucket, ok := h.buckets[key]; ok {
+		tokens = limit.refillTokens(bucket.Tokens, bucket.UpdatedAt, now)
+	}
+
+	allowed := tokens >= 1
+	if allowed {
+		tokens--
+	}
+	h.buckets[key] = rateLimitBucket{
+		Key:       key,
+		Tokens:    tokens,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(limit.untilTokens(tokens, float64(limit.Limit))),
+	}
+
+	return limit.result(allowed, tokens), nil
+}
+
+// prune drops the buckets that are full again once a minute, like the TTL index of the mongo handler
+func (h *RateLimitMemoryDbHandler) prune(now time.Time) {
+	if now.Sub(h.prunedAt) < time.Minute {
+		return
+	}
+
+	for key, bucket := range h.buckets {
+		if !bucket.ExpiresAt.After(now) {
+			delete(h.buckets, key)
+		}
+	}
+	h.prunedAt = now
+}
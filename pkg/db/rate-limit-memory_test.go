@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMemoryDbHandler(t *testing.T) {
+	t.Parallel()
+
+	limit := RateLimit{Limit: 2, Window: time.Minute}
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Takes tokens until the bucket is empty", func(t *testing.T) {
+		ctx := context.Background()
+		h := RateLimitMemoryDbHandler{}
+
+		for i, want := range []RateLimitResult{
+			{Allowed: true, Remaining: 1, Reset: 30 * time.Second},
+			{Allowed: true, Remaining: 0, Reset: time.Minute, RetryAfter: 30 * time.Second},
+			{Allowed: false, Remaining: 0, Reset: time.Minute, RetryAfter: 30 * time.Second},
+		} {
+			got, err := h.Take(ctx, "ip:127.0.0.1", limit, now)
+			if err != nil {
+				t.Fatalf("RateLimitMemoryDbHandler.Take() error = %v, wantErr %v", err, false)
+			}
+			if *got != want {
+				t.Errorf("RateLimitMemoryDbHandler.Take() #%d = %+v, want %+v", i, *got, want)
+			}
+		}
+
+		if got, _ := h.Take(ctx, "ip:127.0.0.2", limit, now); !got.Allowed {
+			t.Errorf("RateLimitMemoryDbHandler.Take() of another key = %+v, want allowed", *got)
+		}
+	})
+
+	t.Run("Refills the bucket over time", func(t *testing.T) {
+		ctx := context.Background()
+		h := RateLimitMemoryDbHandler{}
+
+		h.Take(ctx, "user", limit, now)
+		h.Take(ctx, "user", limit, now)
+		if got, _ := h.Take(ctx, "user", limit, now.Add(30*time.Second)); !got.Allowed || got.Remaining != 0 {
+			t.Errorf("RateLimitMemoryDbHandler.Take() after half the window = %+v, want one refilled token", *got)
+		}
+		if got, _ := h.Take(ctx, "user", limit, now.Add(time.Hour)); got.Remaining != 1 {
+			t.Errorf("RateLimitMemoryDbHandler.Take() after an hour = %+v, want a full bucket", *got)
+		}
+	})
+}
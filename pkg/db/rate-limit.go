@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RateLimitDbHandlerInterface stores the token buckets of the rate limiter. Every bucket holds up to Limit tokens
+// and is refilled at Limit tokens per Window, each request takes a token.
+type RateLimitDbHandlerInterface interface {
+	// Take refills the bucket of the key for the time passed since it was last used and takes a token when there is one
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (*RateLimitResult, error)
+}
+
+// RateLimit allows Limit requests per Window, which can all be made at once
+type RateLimit struct {
+	Limit  int
+	Window time.Duration
+}
+
+// RateLimitResult tells whether a request is allowed and how the bucket of its client looks afterwards
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available, zero while there are tokens left
+	RetryAfter time.Duration
+}
+
+// refillTokens returns the tokens in a bucket that had tokens at updatedAt
+func (l RateLimit) refillTokens(tokens float64, updatedAt time.Time, now time.Time) float64 {
+	refilled := tokens + float64(now.Sub(updatedAt))/float64(l.Window)*float64(l.Limit)
+	return math.Max(0, math.Min(float64(l.Limit), refilled))
+}
+
+// untilTokens is the time it takes to refill the bucket from tokens to target
+func (l RateLimit) untilTokens(tokens float64, target float64) time.Duration {
+	if tokens >= target {
+		return 0
+	}
+	return time.Duration(math.Ceil((target - tokens) / float64(l.Limit) * float64(l.Window)))
+}
+
+func (l RateLimit) result(allowed bool, tokens float64) *RateLimitResult {
+	return &RateLimitResult{
+		Allowed:    allowed,
+		Remaining:  int(math.Floor(tokens)),
+		Reset:      l.untilTokens(tokens, float64(l.Limit)),
+		RetryAfter: l.untilTokens(tokens, 1),
+	}
+}
+
+// rateLimitBucket is the state of a bucket as it is stored, buckets expire once they would be full again
+type rateLimitBucket struct {
+	Key       string    `bson:"_id"`
+	Tokens    float64   `bson:"tokens"`
+	Allowed   bool      `bson:"allowed"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+// DefaultRateLimitsCollection is the collection of the rate limit buckets when RateLimitDbHandler.Collection is empty
+const DefaultRateLimitsCollection = "rate_limits"
+
+// RateLimitDbHandler is the mongo backed implementation of RateLimitDbHandlerInterface, it shares the buckets between replicas
+type RateLimitDbHandler struct {
+	// Collection is the name of the collection the rate limit buckets are stored in, DefaultRateLimitsCollection when empty
+	Collection string
+
+	coll *mongo.Collection
+}
+
+func (h *RateLimitDbHandler) New(context context.Context, database *mongo.Database) error {
+	h.coll = database.Collection(orDefault(h.Collection, DefaultRateLimitsCollection))
+
+	// full buckets are the same as missing ones, so they are left to expire
+	_, err := h.coll.Indexes().CreateOne(context, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// Take refills and takes a token in a single update, so concurrent requests of replicas can't take the same token
+func (h *RateLimitDbHandler) Take(context context.Context, key string, limit RateLimit, now time.Time) (*RateLimitResult, error) {
+	now = now.UTC().Truncate(time.Millisecond)
+	size := float64(limit.Limit)
+	window := float64(limit.Window.Milliseconds())
+
+	// mirrors refillTokens, subtracting dates yields milliseconds
+	refilled := bson.M{"$add": bson.A{
+		bson.M{"$ifNull": bson.A{"$tokens", size}},
+		bson.M{"$multiply": bson.A{
+			bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updatedAt", now}}}}, window}},
+			size,
+		}},
+	}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": bson.M{"$max": bson.A{0, bson.M{"$min": bson.A{size, refilled}}}}, "updatedAt": now}}},
+		{{Key: "$set", Value: bson.M{"allowed": bson.M{"$gte": bson.A{"$tokens", 1}}}}},
+		{{Key: "$set", Value: bson.M{"tokens": bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}}}}},
+		{{Key: "$set", Value: bson.M{"expiresAt": bson.M{"$add": bson.A{
+			now,
+			bson.M{"$multiply": bson.A{bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{size, "$tokens"}}, size}}, window}},
+		}}}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var bucket rateLimitBucket
+	err := h.coll.FindOneAndUpdate(context, bson.D{{Key: "_id", Value: key}}, update, opts).Decode(&bucket)
+	if mongo.IsDuplicateKeyError(err) {
+		// another replica created the bucket at the same time, which the update can apply to now
+		err = h.coll.FindOneAndUpdate(context, bson.D{{Key: "_id", Value: key}}, update, opts).Decode(&bucket)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return limit.result(bucket.Allowed, bucket.Tokens), nil
+}
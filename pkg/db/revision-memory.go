@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RevisionMemoryDbHandler is an in-process implementation of RevisionDbHandlerInterface. The zero value is ready to use.
+type RevisionMemoryDbHandler struct {
+	mu        sync.RWMutex
+	revisions map[primitive.ObjectID][]TodoItemRevision
+}
+
+func (h *RevisionMemoryDbHandler) InsertRevision(context context.Context, revision *TodoItemRevision) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.revisions == nil {
+		h.revisions = map[primitive.ObjectID][]TodoItemRevision{}
+	}
+
+	// same as the unique index of the mongo handler
+	revisions := h.revisions[revision.ItemId]
+	if slices.ContainsFunc(revisions, func(r TodoItemRevision) bool { return r.Revision == revision.Revision }) {
+		return fmt.Errorf("revision %d of item %s already exists", revision.Revision, revision.ItemId.Hex())
+	}
+
+	stored := *revision
+	if stored.Id.IsZero() {
+		stored.Id = primitive.NewObjectID()
+	}
+	stored.Item = copyTodoItem(stored.Item)
+	stored.Changes = slices.Clone(stored.Changes)
+
+	revisions = append(revisions, stored)
+	slices.SortFunc(revisions, func(a, b TodoItemRevision) int { return int(a.Revision - b.Revision) })
+	h.revisions[revision.ItemId] = revisions
+	return nil
+}
+
+func (h *RevisionMemoryDbHandler) FindRevisions(context context.Context, itemId primitive.ObjectID) ([]TodoItemRevision, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	revisions := []TodoItemRevision{}
+	for _, r := range h.revisions[itemId] {
+		r.Item = copyTodoItem(r.Item)
+		revisions = append(revisions, r)
+	}
+
+	return revisions, nil
+}
+
+func (h *RevisionMemoryDbHandler) FindRevision(context context.Context, itemId primitive.ObjectID, revision int64) (*TodoItemRevision, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, r := range h.revisions[itemId] {
+		if r.Revision == revision {
+			r.Item = copyTodoItem(r.Item)
+			return &r, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// actions a revision can record
+const (
+	RevisionActionCreate  = "create"
+	RevisionActionUpdate  = "update"
+	RevisionActionDelete  = "delete"
+	RevisionActionRestore = "restore"
+	RevisionActionTrash   = "trash"
+	RevisionActionUntrash = "untrash"
+)
+
+// TodoItemRevision records a single change of an item. Revision equals the version of the item
+// after the change, Item is a snapshot of the item after the change, or right before it for deletes.
+type TodoItemRevision struct {
+	Id           primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	ItemId       primitive.ObjectID `bson:"itemId" json:"itemId"`
+	Revision     int64              `bson:"revision" json:"revision"`
+	Action       string             `bson:"action" json:"action"`
+	Actor        string             `bson:"actor" json:"actor"`
+	Time         time.Time          `bson:"time" json:"time"`
+	Changes      []FieldChange      `bson:"changes" json:"changes"`
+	RestoredFrom *int64             `bson:"restoredFrom,omitempty" json:"restoredFrom,omitempty"`
+	Item         TodoItemDb         `bson:"item" json:"item"`
+}
+
+// FieldChange is the value of a single field before and after a change, using the json field names
+type FieldChange struct {
+	Field string `bson:"field" json:"field"`
+	From  any    `bson:"from" json:"from"`
+	To    any    `bson:"to" json:"to"`
+}
+
+// RevisionDbHandlerInterface stores the revisions of items
+type RevisionDbHandlerInterface interface {
+	InsertRevision(context.Context, *TodoItemRevision) error
+	// FindRevisions returns all revisions of an item, oldest first
+	FindRevisions(context.Context, primitive.ObjectID) ([]TodoItemRevision, error)
+	// FindRevision returns a single revision of an item, or nil when it doesn't exist
+	FindRevision(context.Context, primitive.ObjectID, int64) (*TodoItemRevision, error)
+}
+
+// DefaultRevisionsCollection is the collection of the revisions when RevisionDbHandler.Collection is empty
+const DefaultRevisionsCollection = "articles_history"
+
+// RevisionDbHandler is the mongo backed implementation of RevisionDbHandlerInterface
+type RevisionDbHandler struct {
+	// Collection is the name of the collection the revisions are stored in, DefaultRevisionsCollection when empty
+	Collection string
+
+	coll *mongo.Collection
+}
+
+func (h *RevisionDbHandler) New(context context.Context, database *mongo.Database) error {
+	h.coll = database.Collection(orDefault(h.Collection, DefaultRevisionsCollection))
+
+	// an item can only have a single revision with a given number
+	_, err := h.coll.Indexes().CreateOne(context, mongo.IndexModel{
+		Keys:    bson.D{{Key: "itemId", Value: 1}, {Key: "revision", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (h *RevisionDbHandler) InsertRevision(context context.Context, revision *TodoItemRevision) error {
+	_, err := h.coll.InsertOne(context, revision)
+	return err
+}
+
+func (h *RevisionDbHandler) FindRevisions(context context.Context, itemId primitive.ObjectID) ([]TodoItemRevision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "revision", Value: 1}})
+	cur, err := h.coll.Find(context, bson.M{"itemId": itemId}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []TodoItemRevision{}
+	if err := cur.All(context, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (h *RevisionDbHandler) FindRevision(context context.Context, itemId primitive.ObjectID, revision int64) (*TodoItemRevision, error) {
+	filter := bson.D{{Key: "itemId", Value: itemId}, {Key: "revision", Value: revision}}
+	var result TodoItemRevision
+	err := h.coll.FindOne(context, filter).Decode(&result)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
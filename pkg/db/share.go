@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// roles users can have on items and lists, each role allows everything the previous ones do
+const (
+	// RoleViewer can read
+	RoleViewer = "viewer"
+	// RoleEditor can change
+	RoleEditor = "editor"
+	// RoleOwner can delete and share
+	RoleOwner = "owner"
+)
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleEditor: 2, RoleOwner: 3}
+
+// RoleAllows tells whether role allows what required allows, an empty role allows nothing
+func RoleAllows(role string, required string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[required]
+}
+
+// Share gives a user a role on an item or list they don't own
+type Share struct {
+	User primitive.ObjectID `bson:"user" json:"user"`
+	Role string             `bson:"role" json:"role"`
+}
+
+type listRolesKey struct{}
+
+// WithListRoles stores the roles of the user on the lists they own or that are shared with them,
+// a role on a list is a role on the items of the list as well
+func WithListRoles(ctx context.Context, roles map[primitive.ObjectID]string) context.Context {
+	return context.WithValue(ctx, listRolesKey{}, roles)
+}
+
+func listRolesFrom(ctx context.Context) map[primitive.ObjectID]string {
+	roles, _ := ctx.Value(listRolesKey{}).(map[primitive.ObjectID]string)
+	return roles
+}
+
+// ListRole is the role of the user of the context on the list, empty when they have no access.
+// Contexts that aren't scoped to a user own everything.
+func ListRole(ctx context.Context, list *TodoListDb) string {
+	user, ok := OwnerFrom(ctx)
+	if !ok || list.Owner == user {
+		return RoleOwner
+	}
+	return shareRole(list.Shares, user)
+}
+
+// ItemRole is the role of the user of the context on the item, the highest of its shares and the role on its list.
+// It is empty when they have no access, contexts that aren't scoped to a user own everything.
+func ItemRole(ctx context.Context, item *TodoItemDb) string {
+	user, ok := OwnerFrom(ctx)
+	if !ok || item.Owner == user {
+		return RoleOwner
+	}
+
+	role := shareRole(item.Shares, user)
+	if item.ListId != nil {
+		if listRole := listRolesFrom(ctx)[*item.ListId]; roleRanks[listRole] > roleRanks[role] {
+			role = listRole
+		}
+	}
+	return role
+}
+
+// listRoles maps the lists to the role of the user of the context on them
+func listRoles(ctx context.Context, lists []TodoListDb) map[primitive.ObjectID]string {
+	roles := map[primitive.ObjectID]string{}
+	for _, list := range lists {
+		roles[list.Id] = ListRole(ctx, &list)
+	}
+	return roles
+}
+
+func shareRole(shares []Share, user primitive.ObjectID) string {
+	for _, share := range shares {
+		if share.User == user {
+			return share.Role
+		}
+	}
+	return ""
+}
+
+// visibleItems adds the condition to a mongo filter that the user of the context has a role on the items
+func visibleItems(ctx context.Context, filter bson.D) bson.D {
+	user, ok := OwnerFrom(ctx)
+	if !ok {
+		return filter
+	}
+
+	conditions := bson.A{bson.M{"owner": user}, bson.M{"shares.user": user}}
+	if roles := listRolesFrom(ctx); len(roles) > 0 {
+		lists := []primitive.ObjectID{}
+		for id := range roles {
+			lists = append(lists, id)
+		}
+		conditions = append(conditions, bson.M{"listId": bson.M{"$in": lists}})
+	}
+	return append(filter, bson.E{Key: "$or", Value: conditions})
+}
+
+// visibleLists adds the condition to a mongo filter that the user of the context has a role on the lists
+func visibleLists(ctx context.Context, filter bson.D) bson.D {
+	user, ok := OwnerFrom(ctx)
+	if !ok {
+		return filter
+	}
+
+	return append(filter, bson.E{Key: "$or", Value: bson.A{bson.M{"owner": user}, bson.M{"shares.user": user}}})
+}
+
+// shareUpdate is an update pipeline giving the user the role, replacing any role they had
+func shareUpdate(share Share) mongo.Pipeline {
+	others := bson.M{"$filter": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$shares", bson.A{}}},
+		"cond":  bson.M{"$ne": bson.A{"$$this.user", share.User}},
+	}}
+	return mongo.Pipeline{{{Key: "$set", Value: bson.M{"shares": bson.M{"$concatArrays": bson.A{others, bson.A{share}}}}}}}
+}
+
+// unshareUpdate takes the role of the user away
+func unshareUpdate(user primitive.ObjectID) bson.M {
+	return bson.M{"$pull": bson.M{"shares": bson.M{"user": user}}}
+}
+
+// withShare returns the shares with the role of the user replaced, a nil share removes the role
+func withShare(shares []Share, user primitive.ObjectID, share *Share) []Share {
+	result := []Share{}
+	for _, s := range shares {
+		if s.User != user {
+			result = append(result, s)
+		}
+	}
+
+	if share != nil {
+		result = append(result, *share)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,332 @@
+package db
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TodoItemMemoryDbHandler is an in-process implementation of TodoItemDbHandlerInterface.
+// It mirrors the behaviour of the mongo handler and is meant for local development and tests,
+// nothing is persisted between restarts. The zero value is ready to use.
+type TodoItemMemoryDbHandler struct {
+	mu    sync.RWMutex
+	items map[primitive.ObjectID]TodoItemDb
+}
+
+func (h *TodoItemMemoryDbHandler) InsertOne(context context.Context, new *TodoItemDb) (primitive.ObjectID, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.items == nil {
+		h.items = map[primitive.ObjectID]TodoItemDb{}
+	}
+
+	item := normalizeTodoItem(*new)
+	item.Owner = ownerOf(context, item.Owner)
+	if item.Id.IsZero() {
+		item.Id = primitive.NewObjectID()
+	}
+	h.items[item.Id] = item
+
+	return item.Id, nil
+}
+
+func (h *TodoItemMemoryDbHandler) FindOneById(context context.Context, id primitive.ObjectID) (*TodoItemDb, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	item, ok := h.items[id]
+	if !ok || !visible(context, &item) {
+		return nil, nil
+	}
+
+	item = copyTodoItem(item)
+	return &item, nil
+}
+
+func (h *TodoItemMemoryDbHandler) FindAll(context context.Context, filter TodoItemFilter, page PageOptions) (*TodoItemPage, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	results := []TodoItemDb{}
+	for _, item := range h.items {
+		if visible(context, &item) && filter.matches(&item) && (page.After == nil || page.After.precedes(&item, page.Sort)) {
+			results = append(results, copyTodoItem(item))
+		}
+	}
+
+	slices.SortFunc(results, func(a, b TodoItemDb) int { return compareBySort(&a, &b, page.Sort) })
+	if page.Limit > 0 && len(results) > page.Limit+1 {
+		results = results[:page.Limit+1]
+	}
+
+	return newTodoItemPage(results, page), nil
+}
+
+func (h *TodoItemMemoryDbHandler) Count(context context.Context, filter TodoItemFilter) (int64, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var count int64
+	for _, item := range h.items {
+		if visible(context, &item) && filter.matches(&item) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (h *TodoItemMemoryDbHandler) AddLabels(context context.Context, id primitive.ObjectID, labels ...string) (*TodoItemDb, error) {
+	return h.update(context, id, AnyVersion, notTrashed, func(item TodoItemDb) TodoItemDb {
+		return addLabels(item, labels)
+	})
+}
+
+func (h *TodoItemMemoryDbHandler) AddLabelsToMany(context context.Context, ids []primitive.ObjectID, labels ...string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range ids {
+		if item, ok := h.items[id]; ok && visible(context, &item) && notTrashed.matches(&item) {
+			item = addLabels(item, labels)
+			item.Version++
+			h.items[id] = item
+		}
+	}
+
+	return nil
+}
+
+func (h *TodoItemMemoryDbHandler) MoveToList(context context.Context, ids []primitive.ObjectID, listId primitive.ObjectID) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range ids {
+		if item, ok := h.items[id]; ok && visible(context, &item) && notTrashed.matches(&item) {
+			item.ListId = &listId
+			item.Version++
+			h.items[id] = item
+		}
+	}
+
+	return nil
+}
+
+func (h *TodoItemMemoryDbHandler) RemoveLabel(context context.Context, id primitive.ObjectID, label string) (*TodoItemDb, error) {
+	return h.update(context, id, AnyVersion, notTrashed, func(item TodoItemDb) TodoItemDb {
+		item.Labels = slices.DeleteFunc(slices.Clone(item.Labels), func(l string) bool { return l == label })
+		return item
+	})
+}
+
+func (h *TodoItemMemoryDbHandler) TrashOneById(context context.Context, id primitive.ObjectID, version int64) (*TodoItemDb, error) {
+	return h.update(context, id, version, notTrashed, func(item TodoItemDb) TodoItemDb {
+		deletedAt := trashedAt()
+		item.DeletedAt = &deletedAt
+		return item
+	})
+}
+
+func (h *TodoItemMemoryDbHandler) UntrashOneById(context context.Context, id primitive.ObjectID, version int64) (*TodoItemDb, error) {
+	return h.update(context, id, version, trashed, func(item TodoItemDb) TodoItemDb {
+		item.DeletedAt = nil
+		return item
+	})
+}
+
+func (h *TodoItemMemoryDbHandler) DeleteOneById(context context.Context, id primitive.ObjectID, version int64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	item, ok := h.items[id]
+	if !ok || !visible(context, &item) {
+		return nil
+	}
+
+	if !matchesVersion(&item, version) {
+		return ErrVersionConflict
+	}
+
+	delete(h.items, id)
+	return nil
+}
+
+func (h *TodoItemMemoryDbHandler) UpdateOneById(context context.Context, id primitive.ObjectID, update *TodoItemDb, version int64) (*TodoItemDb, error) {
+	return h.PatchOneById(context, id, replaceTodoItem(update), version)
+}
+
+func (h *TodoItemMemoryDbHandler) PatchOneById(context context.Context, id primitive.ObjectID, update *TodoItemUpdate, version int64) (*TodoItemDb, error) {
+	return h.update(context, id, version, notTrashed, func(item TodoItemDb) TodoItemDb {
+		return applyTodoItemUpdate(item, update)
+	})
+}
+
+func (h *TodoItemMemoryDbHandler) SetShare(context context.Context, id primitive.ObjectID, share Share) (*TodoItemDb, error) {
+	return h.updateShares(context, id, share.User, &share)
+}
+
+func (h *TodoItemMemoryDbHandler) RemoveShare(context context.Context, id primitive.ObjectID, user primitive.ObjectID) (*TodoItemDb, error) {
+	return h.updateShares(context, id, user, nil)
+}
+
+// updateShares replaces the role of the user, without bumping the version
+func (h *TodoItemMemoryDbHandler) updateShares(context context.Context, id primitive.ObjectID, user primitive.ObjectID, share *Share) (*TodoItemDb, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	item, ok := h.items[id]
+	if !ok || !visible(context, &item) {
+		return nil, nil
+	}
+
+	item.Shares = withShare(item.Shares, user, share)
+	h.items[id] = item
+
+	item = copyTodoItem(item)
+	return &item, nil
+}
+
+func (h *TodoItemMemoryDbHandler) CountChildren(context context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]ChildCount, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	counts := map[primitive.ObjectID]ChildCount{}
+	for _, item := range h.items {
+		if item.ParentId == nil || item.DeletedAt != nil || !visible(context, &item) || !slices.Contains(ids, *item.ParentId) {
+			continue
+		}
+
+		count := counts[*item.ParentId]
+		count.Total++
+		if item.Completed {
+			count.Done++
+		}
+		counts[*item.ParentId] = count
+	}
+
+	return counts, nil
+}
+
+// update applies the change when the user of the context has a role on the item and is in the expected version and trash state, and bumps the version.
+//
+// it returns the updated item or nil when there is no such item
+func (h *TodoItemMemoryDbHandler) update(context context.Context, id primitive.ObjectID, version int64, state trashState, change func(TodoItemDb) TodoItemDb) (*TodoItemDb, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	item, ok := h.items[id]
+	if !ok || !visible(context, &item) || !state.matches(&item) {
+		return nil, nil
+	}
+
+	if !matchesVersion(&item, version) {
+		return nil, ErrVersionConflict
+	}
+
+	item = change(item)
+	item.Version++
+	h.items[id] = item
+
+	item = copyTodoItem(item)
+	return &item, nil
+}
+
+// applyTodoItemUpdate mirrors TodoItemUpdate.bson, empty labels are stored as nil just like an unset field
+func applyTodoItemUpdate(item TodoItemDb, update *TodoItemUpdate) TodoItemDb {
+	if update.Title != nil {
+		item.Title = *update.Title
+	}
+	if update.DueDate != nil {
+		item.DueDate = *update.DueDate
+	}
+	if update.Labels != nil {
+		item.Labels = nil
+		if len(*update.Labels) > 0 {
+			item.Labels = slices.Clone(*update.Labels)
+		}
+	}
+	if update.Description != nil {
+		item.Description = *update.Description
+	}
+	if update.Completed != nil {
+		item.Completed = *update.Completed
+	}
+	if update.Recurrence != nil {
+		item.Recurrence = *update.Recurrence
+	}
+	if update.BlockedBy != nil {
+		item.BlockedBy = nil
+		if len(*update.BlockedBy) > 0 {
+			item.BlockedBy = slices.Clone(*update.BlockedBy)
+		}
+	}
+	if update.ListId != nil {
+		item.ListId = nil
+		if !update.ListId.IsZero() {
+			listId := *update.ListId
+			item.ListId = &listId
+		}
+	}
+	if update.ParentId != nil {
+		item.ParentId = nil
+		if !update.ParentId.IsZero() {
+			parentId := *update.ParentId
+			item.ParentId = &parentId
+		}
+	}
+
+	return normalizeTodoItem(item)
+}
+
+// addLabels behaves like $addToSet with $each, labels that are already present are skipped
+func addLabels(item TodoItemDb, labels []string) TodoItemDb {
+	item.Labels = slices.Clone(item.Labels)
+	for _, label := range labels {
+		if !slices.Contains(item.Labels, label) {
+			item.Labels = append(item.Labels, label)
+		}
+	}
+
+	return item
+}
+
+// copyTodoItem makes sure callers never share the labels slice or any pointer field with the stored item
+func copyTodoItem(item TodoItemDb) TodoItemDb {
+	item.LegacyTitle = item.Title
+	item.Labels = slices.Clone(item.Labels)
+	item.BlockedBy = slices.Clone(item.BlockedBy)
+	item.Shares = slices.Clone(item.Shares)
+	if item.ParentId != nil {
+		parentId := *item.ParentId
+		item.ParentId = &parentId
+	}
+	if item.ListId != nil {
+		listId := *item.ListId
+		item.ListId = &listId
+	}
+	if item.DeletedAt != nil {
+		deletedAt := *item.DeletedAt
+		item.DeletedAt = &deletedAt
+	}
+	return item
+}
+
+// normalizeTodoItem copies the item and stores the due date the way mongo does, in UTC with millisecond precision
+func normalizeTodoItem(item TodoItemDb) TodoItemDb {
+	item = copyTodoItem(item)
+	item.DueDate = item.DueDate.UTC().Truncate(time.Millisecond)
+	if item.DeletedAt != nil {
+		*item.DeletedAt = item.DeletedAt.UTC().Truncate(time.Millisecond)
+	}
+	return item
+}
+
+// visible tells whether the user of the context has a role on the item
+func visible(context context.Context, item *TodoItemDb) bool {
+	return ItemRole(context, item) != ""
+}
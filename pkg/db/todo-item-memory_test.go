@@ -0,0 +1,462 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTodoItemMemoryDbHandler_InsertOne(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Successfully inserted one item", func(t *testing.T) {
+		ctx := context.Background()
+		h := TodoItemMemoryDbHandler{}
+
+		item := &TodoItemDb{
+			Title:       "Test_Title",
+			Description: "Test_Description",
+			Labels:      []string{"Test_Label"},
+			DueDate:     time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond), // stored with millisecond precision like mongo
+		}
+		id, err := h.InsertOne(ctx, item)
+		if err != nil {
+			t.Errorf("TodoItemMemoryDbHandler.InsertOne() error = %v, wantErr %v", err, false)
+			return
+		}
+
+		createdItem, err := h.FindOneById(ctx, id)
+		if err != nil {
+			t.Errorf("TodoItemMemoryDbHandler.FindOneById() error = %v, wantErr %v", err, false)
+			return
+		}
+
+		want := *item
+		want.Id = id
+		want.LegacyTitle = item.Title
+		if !reflect.DeepEqual(*createdItem, want) {
+			t.Errorf("TodoItemMemoryDbHandler.InsertOne() = %v, want %v", *createdItem, want)
+		}
+	})
+
+	t.Run("Stored item is not shared with the caller", func(t *testing.T) {
+		ctx := context.Background()
+		h := TodoItemMemoryDbHandler{}
+
+		item := &TodoItemDb{Title: "Test_Title", Labels: []string{"Test_Label"}}
+		id, _ := h.InsertOne(ctx, item)
+		item.Labels[0] = "Changed"
+
+		createdItem, _ := h.FindOneById(ctx, id)
+		if createdItem.Labels[0] != "Test_Label" {
+			t.Errorf("TodoItemMemoryDbHandler.InsertOne() labels = %v, want %v", createdItem.Labels, []string{"Test_Label"})
+		}
+	})
+}
+
+func TestTodoItemMemoryDbHandler_FindOneById(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Successfully found nothing with non-existing item", func(t *testing.T) {
+		h := TodoItemMemoryDbHandler{}
+
+		found, err := h.FindOneById(context.Background(), primitive.NewObjectID())
+		if err != nil {
+			t.Errorf("TodoItemMemoryDbHandler.FindOneById() error = %v, wantErr %v", err, false)
+			return
+		}
+
+		if found != nil {
+			t.Errorf("TodoItemMemoryDbHandler.FindOneById() = %v, want %v", *found, nil)
+		}
+	})
+}
+
+func TestTodoItemMemoryDbHandler_FindAll(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+	for _, title := range []string{"first", "second", "third"} {
+		if _, err := h.InsertOne(ctx, &TodoItemDb{Title: title}); err != nil {
+			t.Fatalf("TodoItemMemoryDbHandler.InsertOne() error = %v, wantErr %v", err, false)
+		}
+	}
+
+	t.Run("Returns items in insertion order", func(t *testing.T) {
+		page, err := h.FindAll(ctx, TodoItemFilter{}, PageOptions{Limit: 10})
+		if err != nil {
+			t.Errorf("TodoItemMemoryDbHandler.FindAll() error = %v, wantErr %v", err, false)
+			return
+		}
+
+		if page.Truncated {
+			t.Errorf("TodoItemMemoryDbHandler.FindAll() truncated = %v, want %v", page.Truncated, false)
+		}
+
+		var titles []string
+		for _, item := range page.Items {
+			titles = append(titles, item.Title)
+		}
+		if want := []string{"first", "second", "third"}; !reflect.DeepEqual(titles, want) {
+			t.Errorf("TodoItemMemoryDbHandler.FindAll() = %v, want %v", titles, want)
+		}
+	})
+
+	t.Run("Continues after the last item of the previous page", func(t *testing.T) {
+		first, err := h.FindAll(ctx, TodoItemFilter{}, PageOptions{Limit: 2})
+		if err != nil {
+			t.Errorf("TodoItemMemoryDbHandler.FindAll() error = %v, wantErr %v", err, false)
+			return
+		}
+
+		if len(first.Items) != 2 || !first.Truncated || first.Next.Id != first.Items[1].Id {
+			t.Errorf("TodoItemMemoryDbHandler.FindAll() = %+v, want 2 items and a next id", *first)
+			return
+		}
+
+		second, err := h.FindAll(ctx, TodoItemFilter{}, PageOptions{After: first.Next, Limit: 2})
+		if err != nil {
+			t.Errorf("TodoItemMemoryDbHandler.FindAll() error = %v, wantErr %v", err, false)
+			return
+		}
+
+		if len(second.Items) != 1 || second.Truncated || second.Items[0].Title != "third" {
+			t.Errorf("TodoItemMemoryDbHandler.FindAll() = %+v, want only the third item", *second)
+		}
+	})
+}
+
+func TestTodoItemMemoryDbHandler_Labels(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+	id, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Test_Title"})
+	otherId, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Other_Title"})
+
+	h.AddLabels(ctx, id, "Test_Label")
+	item, _ := h.AddLabels(ctx, id, "Test_Label")
+	if want := []string{"Test_Label"}; !reflect.DeepEqual(item.Labels, want) {
+		t.Errorf("TodoItemMemoryDbHandler.AddLabels() labels = %v, want %v", item.Labels, want)
+	}
+
+	if item, _ := h.AddLabels(ctx, primitive.NewObjectID(), "Test_Label"); item != nil {
+		t.Errorf("TodoItemMemoryDbHandler.AddLabels() = %v, want %v", *item, nil)
+	}
+
+	page, _ := h.FindAll(ctx, TodoItemFilter{Labels: []string{"Test_Label"}}, PageOptions{})
+	if len(page.Items) != 1 || page.Items[0].Id != id {
+		t.Errorf("TodoItemMemoryDbHandler.FindAll() = %v, want only %v", page.Items, id)
+	}
+
+	item, _ = h.RemoveLabel(ctx, id, "Test_Label")
+	if len(item.Labels) != 0 {
+		t.Errorf("TodoItemMemoryDbHandler.RemoveLabel() labels = %v, want none", item.Labels)
+	}
+
+	page, _ = h.FindAll(ctx, TodoItemFilter{Labels: []string{"Test_Label"}}, PageOptions{})
+	if len(page.Items) != 0 {
+		t.Errorf("TodoItemMemoryDbHandler.RemoveLabel() left %v", page.Items)
+	}
+
+	h.AddLabelsToMany(ctx, []primitive.ObjectID{id, otherId, primitive.NewObjectID()}, "Bulk_Label", "Other_Label")
+	page, _ = h.FindAll(ctx, TodoItemFilter{Labels: []string{"Bulk_Label", "Other_Label"}, MatchAllLabels: true}, PageOptions{})
+	if len(page.Items) != 2 {
+		t.Errorf("TodoItemMemoryDbHandler.AddLabelsToMany() labelled %v, want both items", page.Items)
+	}
+}
+
+func TestTodoItemMemoryDbHandler_UpdateOneById(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+	id, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Test_Title", Labels: []string{"Test_Label"}, Completed: true})
+
+	item, err := h.UpdateOneById(ctx, id, &TodoItemDb{Title: "New_Title"}, 0)
+	if err != nil {
+		t.Errorf("TodoItemMemoryDbHandler.UpdateOneById() error = %v, wantErr %v", err, false)
+		return
+	}
+
+	// all writable fields are replaced and the version is bumped
+	want := TodoItemDb{Id: id, Title: "New_Title", LegacyTitle: "New_Title", Version: 1}
+	if !reflect.DeepEqual(*item, want) {
+		t.Errorf("TodoItemMemoryDbHandler.UpdateOneById() = %v, want %v", *item, want)
+	}
+
+	t.Run("Rejects an outdated version", func(t *testing.T) {
+		_, err := h.UpdateOneById(ctx, id, &TodoItemDb{Title: "Other_Title"}, 0)
+		if err != ErrVersionConflict {
+			t.Errorf("TodoItemMemoryDbHandler.UpdateOneById() error = %v, want %v", err, ErrVersionConflict)
+		}
+	})
+
+	t.Run("Skips the check with any version", func(t *testing.T) {
+		item, err := h.UpdateOneById(ctx, id, &TodoItemDb{Title: "Other_Title"}, AnyVersion)
+		if err != nil || item.Version != 2 {
+			t.Errorf("TodoItemMemoryDbHandler.UpdateOneById() = %v, %v, want version 2", item, err)
+		}
+	})
+}
+
+func TestTodoItemMemoryDbHandler_PatchOneById(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+	id, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Test_Title", Labels: []string{"Test_Label"}, Completed: true})
+
+	completed := false
+	labels := []string{}
+	item, err := h.PatchOneById(ctx, id, &TodoItemUpdate{Completed: &completed, Labels: &labels}, AnyVersion)
+	if err != nil {
+		t.Errorf("TodoItemMemoryDbHandler.PatchOneById() error = %v, wantErr %v", err, false)
+		return
+	}
+
+	// zero values are written, untouched fields are kept
+	want := TodoItemDb{Id: id, Title: "Test_Title", LegacyTitle: "Test_Title", Version: 1}
+	if !reflect.DeepEqual(*item, want) {
+		t.Errorf("TodoItemMemoryDbHandler.PatchOneById() = %v, want %v", *item, want)
+	}
+
+	if item, _ := h.PatchOneById(ctx, primitive.NewObjectID(), &TodoItemUpdate{}, 0); item != nil {
+		t.Errorf("TodoItemMemoryDbHandler.PatchOneById() = %v, want %v", *item, nil)
+	}
+}
+
+func TestTodoItemMemoryDbHandler_DeleteOneById(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+	id, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Test_Title"})
+
+	if err := h.DeleteOneById(ctx, id, 1); err != ErrVersionConflict {
+		t.Errorf("TodoItemMemoryDbHandler.DeleteOneById() error = %v, want %v", err, ErrVersionConflict)
+		return
+	}
+
+	if err := h.DeleteOneById(ctx, id, 0); err != nil {
+		t.Errorf("TodoItemMemoryDbHandler.DeleteOneById() error = %v, wantErr %v", err, false)
+		return
+	}
+
+	if item, _ := h.FindOneById(ctx, id); item != nil {
+		t.Errorf("TodoItemMemoryDbHandler.DeleteOneById() left %v", *item)
+	}
+}
+
+func TestTodoItemMemoryDbHandler_TrashOneById(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+	id, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Test_Title"})
+
+	if _, err := h.TrashOneById(ctx, id, 1); err != ErrVersionConflict {
+		t.Errorf("TodoItemMemoryDbHandler.TrashOneById() error = %v, want %v", err, ErrVersionConflict)
+		return
+	}
+
+	item, err := h.TrashOneById(ctx, id, 0)
+	if err != nil || item == nil || item.DeletedAt == nil || item.Version != 1 {
+		t.Errorf("TodoItemMemoryDbHandler.TrashOneById() = %v, %v, want a trashed item in version 1", item, err)
+		return
+	}
+
+	// trashed items can't be changed or trashed again, whatever version is given
+	if item, err := h.PatchOneById(ctx, id, &TodoItemUpdate{}, 0); item != nil || err != nil {
+		t.Errorf("TodoItemMemoryDbHandler.PatchOneById() = %v, %v, want %v", item, err, nil)
+	}
+	if item, err := h.TrashOneById(ctx, id, 0); item != nil || err != nil {
+		t.Errorf("TodoItemMemoryDbHandler.TrashOneById() = %v, %v, want %v", item, err, nil)
+	}
+
+	page, _ := h.FindAll(ctx, TodoItemFilter{}, PageOptions{})
+	if len(page.Items) != 0 {
+		t.Errorf("TodoItemMemoryDbHandler.FindAll() = %v, want no items", page.Items)
+	}
+	page, _ = h.FindAll(ctx, TodoItemFilter{Trashed: true}, PageOptions{})
+	if len(page.Items) != 1 {
+		t.Errorf("TodoItemMemoryDbHandler.FindAll() of the trash = %v, want the trashed item", page.Items)
+	}
+
+	item, err = h.UntrashOneById(ctx, id, 1)
+	if err != nil || item == nil || item.DeletedAt != nil || item.Version != 2 {
+		t.Errorf("TodoItemMemoryDbHandler.UntrashOneById() = %v, %v, want a restored item in version 2", item, err)
+	}
+}
+
+func TestTodoItemMemoryDbHandler_CountChildren(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+	parent, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Parent"})
+	childless, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Childless"})
+	h.InsertOne(ctx, &TodoItemDb{Title: "Done", ParentId: &parent, Completed: true})
+	h.InsertOne(ctx, &TodoItemDb{Title: "Open", ParentId: &parent})
+	trashed, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Trashed", ParentId: &parent})
+	h.TrashOneById(ctx, trashed, AnyVersion)
+
+	counts, err := h.CountChildren(ctx, []primitive.ObjectID{parent, childless})
+	if err != nil {
+		t.Errorf("TodoItemMemoryDbHandler.CountChildren() error = %v, wantErr %v", err, false)
+		return
+	}
+
+	want := map[primitive.ObjectID]ChildCount{parent: {Done: 1, Total: 2}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("TodoItemMemoryDbHandler.CountChildren() = %v, want %v", counts, want)
+	}
+}
+
+func TestTodoItemMemoryDbHandler_Count(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+	h.InsertOne(ctx, &TodoItemDb{Title: "Open"})
+	h.InsertOne(ctx, &TodoItemDb{Title: "Done", Completed: true})
+	trashed, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Trashed"})
+	h.TrashOneById(ctx, trashed, AnyVersion)
+
+	completed := true
+	tests := []struct {
+		name   string
+		filter TodoItemFilter
+		want   int64
+	}{
+		{"Counts the items outside of the trash", TodoItemFilter{}, 2},
+		{"Counts the matching items", TodoItemFilter{Completed: &completed}, 1},
+		{"Counts the items in the trash", TodoItemFilter{Trashed: true}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, err := h.Count(ctx, tt.filter)
+			if err != nil {
+				t.Errorf("TodoItemMemoryDbHandler.Count() error = %v, wantErr %v", err, false)
+				return
+			}
+			if count != tt.want {
+				t.Errorf("TodoItemMemoryDbHandler.Count() = %v, want %v", count, tt.want)
+			}
+		})
+	}
+}
+
+func TestTodoItemMemoryDbHandler_FindAllFiltered(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+	now := time.Now().UTC()
+	for _, item := range []TodoItemDb{
+		{Title: "Buy groceries", DueDate: now.Add(time.Hour), Labels: []string{"home", "errand"}},
+		{Title: "Write report", DueDate: now.Add(2 * time.Hour), Labels: []string{"work"}, Completed: true},
+		{Title: "Call plumber", DueDate: now.Add(3 * time.Hour), Labels: []string{"home"}},
+	} {
+		h.InsertOne(ctx, &item)
+	}
+
+	notCompleted := false
+	dueBefore := now.Add(150 * time.Minute)
+	tests := []struct {
+		name   string
+		filter TodoItemFilter
+		sort   []SortField
+		want   []string
+	}{
+		{"No filter", TodoItemFilter{}, nil, []string{"Buy groceries", "Write report", "Call plumber"}},
+		{"Not completed", TodoItemFilter{Completed: &notCompleted}, nil, []string{"Buy groceries", "Call plumber"}},
+		{"Due before", TodoItemFilter{DueBefore: &dueBefore}, nil, []string{"Buy groceries", "Write report"}},
+		{"Any label", TodoItemFilter{Labels: []string{"errand", "work"}}, nil, []string{"Buy groceries", "Write report"}},
+		{"All labels", TodoItemFilter{Labels: []string{"home", "errand"}, MatchAllLabels: true}, nil, []string{"Buy groceries"}},
+		{"Title substring", TodoItemFilter{Title: "REPORT"}, nil, []string{"Write report"}},
+		{"Sorted by title", TodoItemFilter{}, []SortField{{Field: "title"}}, []string{"Buy groceries", "Call plumber", "Write report"}},
+		{"Sorted by due date descending", TodoItemFilter{}, []SortField{{Field: "dueDate", Descending: true}}, []string{"Call plumber", "Write report", "Buy groceries"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := h.FindAll(ctx, tt.filter, PageOptions{Sort: tt.sort})
+			if err != nil {
+				t.Errorf("TodoItemMemoryDbHandler.FindAll() error = %v, wantErr %v", err, false)
+				return
+			}
+
+			titles := []string{}
+			for _, item := range page.Items {
+				titles = append(titles, item.Title)
+			}
+			if !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("TodoItemMemoryDbHandler.FindAll() = %v, want %v", titles, tt.want)
+			}
+		})
+	}
+
+	t.Run("Pages through a sorted result", func(t *testing.T) {
+		sort := []SortField{{Field: "title", Descending: true}}
+		titles := []string{}
+		page := PageOptions{Sort: sort, Limit: 1}
+		for {
+			result, err := h.FindAll(ctx, TodoItemFilter{}, page)
+			if err != nil {
+				t.Fatalf("TodoItemMemoryDbHandler.FindAll() error = %v, wantErr %v", err, false)
+			}
+			for _, item := range result.Items {
+				titles = append(titles, item.Title)
+			}
+			if !result.Truncated {
+				break
+			}
+
+			// round trip through the token like a client would
+			token, _ := result.Next.Token()
+			page.After, err = ParsePageToken(token, sort)
+			if err != nil {
+				t.Fatalf("ParsePageToken() error = %v, wantErr %v", err, false)
+			}
+		}
+
+		if want := []string{"Write report", "Call plumber", "Buy groceries"}; !reflect.DeepEqual(titles, want) {
+			t.Errorf("TodoItemMemoryDbHandler.FindAll() pages = %v, want %v", titles, want)
+		}
+	})
+}
+
+func TestTodoItemMemoryDbHandler_MoveToList(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	h := TodoItemMemoryDbHandler{}
+
+	live, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Live"})
+	trashed, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Trashed"})
+	h.TrashOneById(ctx, trashed, AnyVersion)
+
+	listId := primitive.NewObjectID()
+	if err := h.MoveToList(ctx, []primitive.ObjectID{live, trashed, primitive.NewObjectID()}, listId); err != nil {
+		t.Fatalf("TodoItemMemoryDbHandler.MoveToList() error = %v, wantErr %v", err, false)
+	}
+
+	item, _ := h.FindOneById(ctx, live)
+	if item.ListId == nil || *item.ListId != listId || item.Version != 1 {
+		t.Errorf("TodoItemMemoryDbHandler.MoveToList() = %v, want list %v at version 1", item, listId)
+	}
+
+	item, _ = h.FindOneById(ctx, trashed)
+	if item.ListId != nil {
+		t.Errorf("TodoItemMemoryDbHandler.MoveToList() moved a trashed item to %v", item.ListId)
+	}
+
+	page, _ := h.FindAll(ctx, TodoItemFilter{ExcludeListIds: []primitive.ObjectID{listId}}, PageOptions{})
+	if len(page.Items) != 0 {
+		t.Errorf("TodoItemMemoryDbHandler.FindAll() excluding the list = %v, want none", page.Items)
+	}
+}
@@ -2,51 +2,307 @@ package db
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// DefaultItemsCollection is the collection of the items when TodoItemDbHandler.Collection is empty
+const DefaultItemsCollection = "articles"
+
+// TodoItemDbHandler is the mongo backed implementation of TodoItemDbHandlerInterface
 type TodoItemDbHandler struct {
+	// Collection is the name of the collection the items are stored in, DefaultItemsCollection when empty
+	Collection string
+
 	coll *mongo.Collection
 }
 
+// an index on the labels array, and ones to look up the children, dependents, list, owner and shares of an item
+var todoItemIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "labels", Value: 1}}},
+	{Keys: bson.D{{Key: "parentId", Value: 1}}},
+	{Keys: bson.D{{Key: "blockedBy", Value: 1}}},
+	{Keys: bson.D{{Key: "listId", Value: 1}}},
+	{Keys: bson.D{{Key: "owner", Value: 1}}},
+	{Keys: bson.D{{Key: "shares.user", Value: 1}}},
+}
+
+// TodoItemDbHandlerInterface is the storage contract the controllers depend on.
+// TodoItemDbHandler implements it on top of mongo, TodoItemMemoryDbHandler keeps everything in-process.
+// All methods only see the items the user stored in the context by WithOwner has a role on, see ItemRole.
 type TodoItemDbHandlerInterface interface {
-	New(context.Context, *mongo.Database) error
 	InsertOne(context.Context, *TodoItemDb) (primitive.ObjectID, error)
 	FindOneById(context.Context, primitive.ObjectID) (*TodoItemDb, error)
-	FindAll(context.Context) (*mongo.Cursor, error)
-	FindByLabel(context.Context, string) (*mongo.Cursor, error)
-	AddLabel(context.Context, primitive.ObjectID, string) error
-	RemoveLabel(context.Context, primitive.ObjectID, string) error
-	DeleteOneById(context.Context, primitive.ObjectID) error
-	UpdateOneById(context.Context, primitive.ObjectID, *TodoItemDb) error
-	ConsumeCursor(*mongo.Cursor, int) (*[]TodoItemDb, error)
+	FindAll(context.Context, TodoItemFilter, PageOptions) (*TodoItemPage, error)
+	// Count returns how many items match the filter
+	Count(context.Context, TodoItemFilter) (int64, error)
+	AddLabels(context.Context, primitive.ObjectID, ...string) (*TodoItemDb, error)
+	AddLabelsToMany(context.Context, []primitive.ObjectID, ...string) error
+	// MoveToList puts the given items into the list, ids that don't exist or are in the trash are ignored
+	MoveToList(context.Context, []primitive.ObjectID, primitive.ObjectID) error
+	RemoveLabel(context.Context, primitive.ObjectID, string) (*TodoItemDb, error)
+	// TrashOneById moves the item to the trash, items in the trash are only returned by FindOneById
+	// and by FindAll when the filter asks for them, and can't be changed until they are restored
+	TrashOneById(context.Context, primitive.ObjectID, int64) (*TodoItemDb, error)
+	// UntrashOneById restores an item from the trash
+	UntrashOneById(context.Context, primitive.ObjectID, int64) (*TodoItemDb, error)
+	// DeleteOneById deletes the item permanently, whether it is in the trash or not
+	DeleteOneById(context.Context, primitive.ObjectID, int64) error
+	UpdateOneById(context.Context, primitive.ObjectID, *TodoItemDb, int64) (*TodoItemDb, error)
+	PatchOneById(context.Context, primitive.ObjectID, *TodoItemUpdate, int64) (*TodoItemDb, error)
+	// SetShare gives the user of the share its role on the item, and returns the updated item or nil when it doesn't exist
+	SetShare(context.Context, primitive.ObjectID, Share) (*TodoItemDb, error)
+	// RemoveShare takes the role of the user on the item away, and returns the updated item or nil when it doesn't exist
+	RemoveShare(context.Context, primitive.ObjectID, primitive.ObjectID) (*TodoItemDb, error)
+	// CountChildren counts the children outside of the trash of each of the given items, items without children are left out
+	CountChildren(context.Context, []primitive.ObjectID) (map[primitive.ObjectID]ChildCount, error)
 }
 
 type TodoItemDb struct {
-	Id          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Title       string             `bson:"string" json:"string"`
-	DueDate     time.Time          `bson:"dueDate" json:"dueDate"`
-	Labels      []string           `bson:"labels,omitempty" json:"labels,omitempty"`
-	Description string             `bson:"description" json:"description"`
-	Completed   bool               `bson:"completed,omitempty" json:"completed,omitempty"`
+	Id    primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Title string             `bson:"title" json:"title"`
+	// LegacyTitle repeats the title under the name it had before, for clients that still read it.
+	// It is set on every item the handlers return, MarshalBSON stores the title under that name.
+	LegacyTitle string    `bson:"-" json:"string"`
+	DueDate     time.Time `bson:"dueDate" json:"dueDate"`
+	Labels      []string  `bson:"labels,omitempty" json:"labels,omitempty"`
+	Description string    `bson:"description" json:"description"`
+	Completed   bool      `bson:"completed,omitempty" json:"completed,omitempty"`
+	// Owner is the user the item belongs to
+	Owner primitive.ObjectID `bson:"owner,omitempty" json:"owner"`
+	// Shares give other users access to the item
+	Shares []Share `bson:"shares,omitempty" json:"shares,omitempty"`
+	// ListId is the list the item belongs to, if any
+	ListId *primitive.ObjectID `bson:"listId,omitempty" json:"listId,omitempty"`
+	// ParentId makes the item a subtask of another item
+	ParentId *primitive.ObjectID `bson:"parentId,omitempty" json:"parentId,omitempty"`
+	// BlockedBy lists the items that have to be completed before this one
+	BlockedBy []primitive.ObjectID `bson:"blockedBy,omitempty" json:"blockedBy,omitempty"`
+	// Recurrence is an RRULE repeating the item from its due date, completing the item moves it to the next occurrence
+	Recurrence string `bson:"recurrence,omitempty" json:"recurrence,omitempty"`
+	// Version is incremented on every change of the item
+	Version int64 `bson:"version" json:"version"`
+	// DeletedAt is set while the item is in the trash
+	DeletedAt *time.Time `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+}
+
+// legacyTitle is the field former versions store the title in. The title is written to it as well until every instance
+// reads title, so instances of former versions see the titles written during a rollout, see Migrations.
+type legacyTitle struct {
+	Title string `bson:"string,omitempty"`
+}
+
+// MarshalBSON stores the title under its former name as well
+func (item TodoItemDb) MarshalBSON() ([]byte, error) {
+	type stored TodoItemDb
+	return bson.Marshal(struct {
+		Item   stored      `bson:",inline"`
+		Legacy legacyTitle `bson:",inline"`
+	}{stored(item), legacyTitle{item.Title}})
+}
+
+// UnmarshalBSON reads the title from its former name as well. When both differ, an instance of a former version
+// changed the title after the item was written by this version, so the former name wins.
+func (item *TodoItemDb) UnmarshalBSON(data []byte) error {
+	type stored TodoItemDb
+	var doc struct {
+		Item   stored      `bson:",inline"`
+		Legacy legacyTitle `bson:",inline"`
+	}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+
+	*item = TodoItemDb(doc.Item)
+	if doc.Legacy.Title != "" {
+		item.Title = doc.Legacy.Title
+	}
+	item.LegacyTitle = item.Title
+	return nil
+}
+
+// ChildCount is the completion rollup of the children of an item
+type ChildCount struct {
+	Done  int `bson:"done" json:"done"`
+	Total int `bson:"total" json:"total"`
+}
+
+// TodoItemUpdate lists the fields to change, nil fields are left untouched.
+// Zero values are written as well, which allows clearing fields, a zero ParentId or ListId clears the parent or list.
+type TodoItemUpdate struct {
+	Title       *string
+	DueDate     *time.Time
+	Labels      *[]string
+	Description *string
+	Completed   *bool
+	Recurrence  *string
+	ParentId    *primitive.ObjectID
+	ListId      *primitive.ObjectID
+	BlockedBy   *[]primitive.ObjectID
+}
+
+// Empty tells whether the update doesn't change any field
+func (u *TodoItemUpdate) Empty() bool {
+	return *u == TodoItemUpdate{}
+}
+
+// bson converts the update into $set and $unset operators, zero values of omitempty fields
+// are unset so the document looks the same as one inserted with those values
+func (u *TodoItemUpdate) bson() bson.M {
+	set := bson.M{}
+	unset := bson.M{}
+
+	if u.Title != nil {
+		set["title"] = *u.Title
+		set["string"] = *u.Title
+	}
+	if u.DueDate != nil {
+		set["dueDate"] = *u.DueDate
+	}
+	if u.Labels != nil {
+		if len(*u.Labels) > 0 {
+			set["labels"] = *u.Labels
+		} else {
+			unset["labels"] = ""
+		}
+	}
+	if u.Description != nil {
+		set["description"] = *u.Description
+	}
+	if u.Completed != nil {
+		if *u.Completed {
+			set["completed"] = true
+		} else {
+			unset["completed"] = ""
+		}
+	}
+	if u.Recurrence != nil {
+		if *u.Recurrence != "" {
+			set["recurrence"] = *u.Recurrence
+		} else {
+			unset["recurrence"] = ""
+		}
+	}
+	if u.BlockedBy != nil {
+		if len(*u.BlockedBy) > 0 {
+			set["blockedBy"] = *u.BlockedBy
+		} else {
+			unset["blockedBy"] = ""
+		}
+	}
+	if u.ParentId != nil {
+		if !u.ParentId.IsZero() {
+			set["parentId"] = *u.ParentId
+		} else {
+			unset["parentId"] = ""
+		}
+	}
+	if u.ListId != nil {
+		if !u.ListId.IsZero() {
+			set["listId"] = *u.ListId
+		} else {
+			unset["listId"] = ""
+		}
+	}
+
+	update := bson.M{"$inc": bson.M{"version": 1}}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+// replaceTodoItem is the update that overwrites all writable fields with the ones of the item
+func replaceTodoItem(item *TodoItemDb) *TodoItemUpdate {
+	labels := item.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+
+	parentId := primitive.NilObjectID
+	if item.ParentId != nil {
+		parentId = *item.ParentId
+	}
+
+	listId := primitive.NilObjectID
+	if item.ListId != nil {
+		listId = *item.ListId
+	}
+
+	blockedBy := item.BlockedBy
+	if blockedBy == nil {
+		blockedBy = []primitive.ObjectID{}
+	}
+
+	return &TodoItemUpdate{
+		Title:       &item.Title,
+		DueDate:     &item.DueDate,
+		Labels:      &labels,
+		Description: &item.Description,
+		Completed:   &item.Completed,
+		Recurrence:  &item.Recurrence,
+		ParentId:    &parentId,
+		ListId:      &listId,
+		BlockedBy:   &blockedBy,
+	}
 }
 
 func (h *TodoItemDbHandler) New(context context.Context, database *mongo.Database) error {
-	h.coll = database.Collection("articles")
+	h.coll = database.Collection(orDefault(h.Collection, DefaultItemsCollection))
 
-	// creates an index on the labels array
-	_, err := h.coll.Indexes().CreateOne(context, mongo.IndexModel{
-		Keys: bson.D{{Key: "labels", Value: 1}},
-	})
+	_, err := h.coll.Indexes().CreateMany(context, todoItemIndexes)
 	return err
 }
 
+// CheckIndexes returns an error when one of the indexes created by New is missing
+func (h *TodoItemDbHandler) CheckIndexes(context context.Context) error {
+	cur, err := h.coll.Indexes().List(context)
+	if err != nil {
+		return err
+	}
+
+	var indexes []struct {
+		Key bson.D `bson:"key"`
+	}
+	if err := cur.All(context, &indexes); err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, index := range indexes {
+		existing[indexKey(index.Key)] = true
+	}
+	for _, model := range todoItemIndexes {
+		if key := indexKey(model.Keys.(bson.D)); !existing[key] {
+			return fmt.Errorf("the index on %s is missing", key)
+		}
+	}
+	return nil
+}
+
+// indexKey names an index by its fields, in order
+func indexKey(keys bson.D) string {
+	fields := []string{}
+	for _, key := range keys {
+		fields = append(fields, key.Key)
+	}
+	return strings.Join(fields, ",")
+}
+
 func (h *TodoItemDbHandler) InsertOne(context context.Context, new *TodoItemDb) (primitive.ObjectID, error) {
-	result, err := h.coll.InsertOne(context, new)
+	item := *new
+	item.Owner = ownerOf(context, item.Owner)
+
+	result, err := h.coll.InsertOne(context, &item)
 	if err != nil {
 		return primitive.NilObjectID, err
 	}
@@ -55,7 +311,7 @@ func (h *TodoItemDbHandler) InsertOne(context context.Context, new *TodoItemDb)
 }
 
 func (h *TodoItemDbHandler) FindOneById(context context.Context, id primitive.ObjectID) (*TodoItemDb, error) {
-	filter := bson.D{{Key: "_id", Value: id}}
+	filter := visibleItems(context, bson.D{{Key: "_id", Value: id}})
 	var article TodoItemDb
 	err := h.coll.FindOne(context, filter).Decode(&article)
 
@@ -69,53 +325,227 @@ func (h *TodoItemDbHandler) FindOneById(context context.Context, id primitive.Ob
 	return &article, nil
 }
 
-func (h *TodoItemDbHandler) FindAll(context context.Context) (*mongo.Cursor, error) {
-	cur, err := h.coll.Find(context, bson.D{{}})
+// FindAll returns a page of the items matching the filter
+func (h *TodoItemDbHandler) FindAll(context context.Context, filter TodoItemFilter, page PageOptions) (*TodoItemPage, error) {
+	query := filter.bson()
+	if _, ok := OwnerFrom(context); ok {
+		query = bson.M{"$and": bson.A{query, visibleItems(context, bson.D{})}}
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: query}}}
+	titleSorted := sortsByTitle(page.Sort)
+	if titleSorted {
+		pipeline = append(pipeline, bson.D{{Key: "$set", Value: bson.M{sortableFields["title"]: titleExpr}}})
+	}
+	if page.After != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: mongoKeyset(page.Sort, page.After)}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: mongoSort(page.Sort)}})
+	if page.Limit > 0 {
+		// fetch one extra item to know if there is a next page
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: page.Limit + 1}})
+	}
+	if titleSorted {
+		pipeline = append(pipeline, bson.D{{Key: "$unset", Value: sortableFields["title"]}})
+	}
+
+	cur, err := h.coll.Aggregate(context, pipeline)
 	if err != nil {
 		return nil, err
 	}
 
-	return cur, nil
-}
-
-func (h *TodoItemDbHandler) FindByLabel(context context.Context, label string) (*mongo.Cursor, error) {
-	filter := bson.M{"labels": label}
-	cur, err := h.coll.Find(context, filter)
+	items, err := consumeCursor(context, cur, 0)
 	if err != nil {
 		return nil, err
 	}
 
-	return cur, nil
+	return newTodoItemPage(*items, page), nil
 }
 
-func (h *TodoItemDbHandler) AddLabel(context context.Context, id primitive.ObjectID, label string) error {
-	update := bson.M{"$addToSet": bson.M{"labels": label}} // should not have duplicate labels
-	_, err := h.coll.UpdateByID(context, id, update)
-	return err
+// Count counts the items matching the filter
+func (h *TodoItemDbHandler) Count(context context.Context, filter TodoItemFilter) (int64, error) {
+	query := filter.bson()
+	if _, ok := OwnerFrom(context); ok {
+		query = bson.M{"$and": bson.A{query, visibleItems(context, bson.D{})}}
+	}
+
+	return h.coll.CountDocuments(context, query)
 }
 
-func (h *TodoItemDbHandler) RemoveLabel(context context.Context, id primitive.ObjectID, label string) error {
-	update := bson.M{"$pull": bson.M{"labels": label}}
-	_, err := h.coll.UpdateByID(context, id, update)
-	return err
+// AddLabels adds the labels to the item and returns the updated item, or nil when it doesn't exist or is in the trash
+func (h *TodoItemDbHandler) AddLabels(context context.Context, id primitive.ObjectID, labels ...string) (*TodoItemDb, error) {
+	update := bson.M{
+		"$addToSet": bson.M{"labels": bson.M{"$each": labels}}, // should not have duplicate labels
+		"$inc":      bson.M{"version": 1},
+	}
+	return h.findOneAndUpdate(context, id, AnyVersion, notTrashed, update)
 }
 
-func (h *TodoItemDbHandler) DeleteOneById(context context.Context, id primitive.ObjectID) error {
-	filter := bson.D{{Key: "_id", Value: id}}
-	_, err := h.coll.DeleteOne(context, filter)
+// AddLabelsToMany adds the labels to all the given items, ids that don't exist or are in the trash are ignored
+func (h *TodoItemDbHandler) AddLabelsToMany(context context.Context, ids []primitive.ObjectID, labels ...string) error {
+	filter := visibleItems(context, notTrashed.filter(bson.D{{Key: "_id", Value: bson.M{"$in": ids}}}))
+	update := bson.M{
+		"$addToSet": bson.M{"labels": bson.M{"$each": labels}},
+		"$inc":      bson.M{"version": 1},
+	}
+	_, err := h.coll.UpdateMany(context, filter, update)
 	return err
 }
 
-func (h *TodoItemDbHandler) UpdateOneById(context context.Context, id primitive.ObjectID, update *TodoItemDb) error {
-	_, err := h.coll.UpdateByID(context, id, bson.M{"$set": update})
+// MoveToList puts the given items into the list, ids that don't exist or are in the trash are ignored
+func (h *TodoItemDbHandler) MoveToList(context context.Context, ids []primitive.ObjectID, listId primitive.ObjectID) error {
+	filter := visibleItems(context, notTrashed.filter(bson.D{{Key: "_id", Value: bson.M{"$in": ids}}}))
+	update := bson.M{
+		"$set": bson.M{"listId": listId},
+		"$inc": bson.M{"version": 1},
+	}
+	_, err := h.coll.UpdateMany(context, filter, update)
 	return err
 }
 
-func (h *TodoItemDbHandler) ConsumeCursor(cur *mongo.Cursor, max int) (*[]TodoItemDb, error) {
+// RemoveLabel removes the label from the item and returns the updated item, or nil when it doesn't exist or is in the trash
+func (h *TodoItemDbHandler) RemoveLabel(context context.Context, id primitive.ObjectID, label string) (*TodoItemDb, error) {
+	update := bson.M{"$pull": bson.M{"labels": label}, "$inc": bson.M{"version": 1}}
+	return h.findOneAndUpdate(context, id, AnyVersion, notTrashed, update)
+}
+
+// TrashOneById moves the item to the trash when it is in the expected version
+// and returns the trashed item, or nil when it doesn't exist or is already in the trash
+func (h *TodoItemDbHandler) TrashOneById(context context.Context, id primitive.ObjectID, version int64) (*TodoItemDb, error) {
+	update := bson.M{"$set": bson.M{"deletedAt": trashedAt()}, "$inc": bson.M{"version": 1}}
+	return h.findOneAndUpdate(context, id, version, notTrashed, update)
+}
+
+// UntrashOneById restores the item from the trash when it is in the expected version
+// and returns the restored item, or nil when it doesn't exist or isn't in the trash
+func (h *TodoItemDbHandler) UntrashOneById(context context.Context, id primitive.ObjectID, version int64) (*TodoItemDb, error) {
+	update := bson.M{"$unset": bson.M{"deletedAt": ""}, "$inc": bson.M{"version": 1}}
+	return h.findOneAndUpdate(context, id, version, trashed, update)
+}
+
+// findOneAndUpdate applies the update atomically when the item is in the expected version and trash state,
+// and returns the item as it is after the update, or nil when there is no such item
+func (h *TodoItemDbHandler) findOneAndUpdate(context context.Context, id primitive.ObjectID, version int64, state trashState, update bson.M) (*TodoItemDb, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var item TodoItemDb
+	filter := visibleItems(context, state.filter(versionFilter(id, version)))
+	err := h.coll.FindOneAndUpdate(context, filter, update, opts).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, h.versionConflict(context, id, version, state)
+		}
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// versionConflict tells apart a missing item from one in another version, after a write matched nothing
+func (h *TodoItemDbHandler) versionConflict(context context.Context, id primitive.ObjectID, version int64, state trashState) error {
+	if version == AnyVersion {
+		return nil
+	}
+
+	item, err := h.FindOneById(context, id)
+	if err != nil {
+		return err
+	}
+
+	if item != nil && state.matches(item) {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// DeleteOneById permanently deletes the item when it is in the expected version
+func (h *TodoItemDbHandler) DeleteOneById(context context.Context, id primitive.ObjectID, version int64) error {
+	result, err := h.coll.DeleteOne(context, visibleItems(context, versionFilter(id, version)))
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return h.versionConflict(context, id, version, anyTrashState)
+	}
+	return nil
+}
+
+// UpdateOneById replaces all writable fields of the item when it is in the expected version
+// and returns the updated item, or nil when it doesn't exist or is in the trash
+func (h *TodoItemDbHandler) UpdateOneById(context context.Context, id primitive.ObjectID, update *TodoItemDb, version int64) (*TodoItemDb, error) {
+	return h.PatchOneById(context, id, replaceTodoItem(update), version)
+}
+
+// PatchOneById changes only the fields set in the update when the item is in the expected version
+// and returns the updated item, or nil when it doesn't exist or is in the trash
+func (h *TodoItemDbHandler) PatchOneById(context context.Context, id primitive.ObjectID, update *TodoItemUpdate, version int64) (*TodoItemDb, error) {
+	return h.findOneAndUpdate(context, id, version, notTrashed, update.bson())
+}
+
+// SetShare replaces the role of the user on the item, shares aren't changes of the item so the version stays the same
+func (h *TodoItemDbHandler) SetShare(context context.Context, id primitive.ObjectID, share Share) (*TodoItemDb, error) {
+	return h.updateShares(context, id, shareUpdate(share))
+}
+
+func (h *TodoItemDbHandler) RemoveShare(context context.Context, id primitive.ObjectID, user primitive.ObjectID) (*TodoItemDb, error) {
+	return h.updateShares(context, id, unshareUpdate(user))
+}
+
+func (h *TodoItemDbHandler) updateShares(context context.Context, id primitive.ObjectID, update any) (*TodoItemDb, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var item TodoItemDb
+	err := h.coll.FindOneAndUpdate(context, visibleItems(context, bson.D{{Key: "_id", Value: id}}), update, opts).Decode(&item)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// CountChildren groups the children outside of the trash by parent, counting the completed ones
+func (h *TodoItemDbHandler) CountChildren(context context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]ChildCount, error) {
+	match := visibleItems(context, notTrashed.filter(bson.D{{Key: "parentId", Value: bson.M{"$in": ids}}}))
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$parentId",
+			"total": bson.M{"$sum": 1},
+			"done":  bson.M{"$sum": bson.M{"$cond": bson.A{"$completed", 1, 0}}},
+		}}},
+	}
+
+	cur, err := h.coll.Aggregate(context, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []struct {
+		ParentId   primitive.ObjectID `bson:"_id"`
+		ChildCount `bson:",inline"`
+	}
+	if err := cur.All(context, &groups); err != nil {
+		return nil, err
+	}
+
+	counts := map[primitive.ObjectID]ChildCount{}
+	for _, group := range groups {
+		counts[group.ParentId] = group.ChildCount
+	}
+	return counts, nil
+}
+
+// consumeCursor decodes up to max items from the cursor and closes it, a max of 0 decodes everything
+func consumeCursor(context context.Context, cur *mongo.Cursor, max int) (*[]TodoItemDb, error) {
+	defer cur.Close(context)
 	results := []TodoItemDb{}
 
 	i := 0
-	for cur.Next(context.TODO()) {
+	for cur.Next(context) {
 		var elem TodoItemDb
 		err := cur.Decode(&elem)
 		if err != nil {
@@ -134,6 +564,5 @@ func (h *TodoItemDbHandler) ConsumeCursor(cur *mongo.Cursor, max int) (*[]TodoIt
 		return nil, err
 	}
 
-	cur.Close(context.TODO())
 	return &results, nil
 }
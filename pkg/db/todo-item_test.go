@@ -7,6 +7,7 @@ import (
 	"time"
 	"todo-list-service/pkg/env"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -66,6 +67,80 @@ func TestTodoItemDbHandler_New(t *testing.T) {
 	})
 }
 
+func TestTodoItemDbHandler_CheckIndexes(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	h, close := createColl(ctx, t)
+	defer close()
+
+	t.Run("Successfully find the created indexes", func(t *testing.T) {
+		if err := h.CheckIndexes(ctx); err != nil {
+			t.Errorf("TodoItemDbHandler.CheckIndexes() error = %v, wantErr %v", err, false)
+		}
+	})
+
+	t.Run("Fail when an index is missing", func(t *testing.T) {
+		h.coll.Indexes().DropOne(ctx, "labels_1")
+		if err := h.CheckIndexes(ctx); err == nil {
+			t.Errorf("TodoItemDbHandler.CheckIndexes() error = %v, wantErr %v", err, true)
+		}
+	})
+}
+
+func TestTodoItemDb_UnmarshalBSON(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		doc  bson.M
+		want string
+	}{
+		{"Reads the title", bson.M{"title": "Test_Title"}, "Test_Title"},
+		{"Reads the title of items that weren't migrated", bson.M{"string": "Test_Title"}, "Test_Title"},
+		{"Prefers the legacy field changed by former versions", bson.M{"title": "Old_Title", "string": "Test_Title"}, "Test_Title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, _ := bson.Marshal(tt.doc)
+			var item TodoItemDb
+			if err := bson.Unmarshal(data, &item); err != nil {
+				t.Fatalf("TodoItemDb.UnmarshalBSON() error = %v, wantErr %v", err, false)
+			}
+			if item.Title != tt.want || item.LegacyTitle != tt.want {
+				t.Errorf("TodoItemDb.UnmarshalBSON() = %+v, want the title %q", item, tt.want)
+			}
+		})
+	}
+
+	t.Run("Reads the title of revisions", func(t *testing.T) {
+		data, _ := bson.Marshal(bson.M{"revision": 1, "item": bson.M{"string": "Test_Title", "labels": bson.A{"home"}}})
+		var revision TodoItemRevision
+		if err := bson.Unmarshal(data, &revision); err != nil {
+			t.Fatalf("TodoItemDb.UnmarshalBSON() error = %v, wantErr %v", err, false)
+		}
+		if revision.Item.Title != "Test_Title" || !reflect.DeepEqual(revision.Item.Labels, []string{"home"}) {
+			t.Errorf("TodoItemDb.UnmarshalBSON() = %+v, want the item of the revision", revision.Item)
+		}
+	})
+}
+
+func TestTodoItemDb_MarshalBSON(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Writes the title under both names", func(t *testing.T) {
+		data, err := bson.Marshal(&TodoItemDb{Title: "Test_Title", Version: 2})
+		if err != nil {
+			t.Fatalf("TodoItemDb.MarshalBSON() error = %v, wantErr %v", err, false)
+		}
+
+		var doc bson.M
+		bson.Unmarshal(data, &doc)
+		if doc["title"] != "Test_Title" || doc["string"] != "Test_Title" || doc["version"] != int64(2) {
+			t.Errorf("TodoItemDb.MarshalBSON() = %v, want the title under both names", doc)
+		}
+	})
+}
+
 func TestTodoItemDbHandler_InsertOne(t *testing.T) {
 	t.Parallel()
 
@@ -94,6 +169,7 @@ func TestTodoItemDbHandler_InsertOne(t *testing.T) {
 		}
 
 		item.Id = createdItem.Id
+		item.LegacyTitle = item.Title
 		if !reflect.DeepEqual(*createdItem, item) {
 			t.Errorf("TodoItemDbHandler.InsertOne() = %v, want %v", *createdItem, item)
 			return
@@ -126,6 +202,7 @@ func TestTodoItemDbHandler_InsertOne(t *testing.T) {
 		}
 
 		item.Id = createdItem.Id
+		item.LegacyTitle = item.Title
 		if !reflect.DeepEqual(*createdItem, item) {
 			t.Errorf("TodoItemDbHandler.InsertOne() = %v, want %v", *createdItem, item)
 			return
@@ -162,6 +239,7 @@ func TestTodoItemDbHandler_FindOneById(t *testing.T) {
 		}
 
 		item.Id = createdItem.Id
+		item.LegacyTitle = item.Title
 		if !reflect.DeepEqual(*createdItem, item) {
 			t.Errorf("TodoItemDbHandler.FindOneById() = %v, want %v", *createdItem, item)
 			return
@@ -189,3 +267,42 @@ func TestTodoItemDbHandler_FindOneById(t *testing.T) {
 }
 
 // TODO: other db tests
+
+func TestTodoItemDbHandler_FindAll(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Sorts and filters items that weren't migrated by their title", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := context.Background()
+		h, close := createColl(ctx, t)
+		defer close()
+
+		h.InsertOne(ctx, &TodoItemDb{Title: "b_Title"})
+		h.coll.InsertOne(ctx, bson.M{"string": "a_Title"})
+		h.coll.InsertOne(ctx, bson.M{"string": "c_Title"})
+
+		sort := []SortField{{Field: "title"}}
+		first, err := h.FindAll(ctx, TodoItemFilter{}, PageOptions{Sort: sort, Limit: 2})
+		if err != nil {
+			t.Fatalf("TodoItemDbHandler.FindAll() error = %v, wantErr %v", err, false)
+		}
+		second, err := h.FindAll(ctx, TodoItemFilter{}, PageOptions{Sort: sort, Limit: 2, After: first.Next})
+		if err != nil {
+			t.Fatalf("TodoItemDbHandler.FindAll() error = %v, wantErr %v", err, false)
+		}
+
+		titles := []string{}
+		for _, item := range append(first.Items, second.Items...) {
+			titles = append(titles, item.Title)
+		}
+		if want := []string{"a_Title", "b_Title", "c_Title"}; !reflect.DeepEqual(titles, want) {
+			t.Errorf("TodoItemDbHandler.FindAll() = %v, want %v", titles, want)
+		}
+
+		filtered, err := h.FindAll(ctx, TodoItemFilter{Title: "C_TI"}, PageOptions{})
+		if err != nil || len(filtered.Items) != 1 || filtered.Items[0].Title != "c_Title" {
+			t.Errorf("TodoItemDbHandler.FindAll() = %+v, %v, want the item that wasn't migrated", filtered, err)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TodoListMemoryDbHandler is an in-process implementation of TodoListDbHandlerInterface. The zero value is ready to use.
+type TodoListMemoryDbHandler struct {
+	mu    sync.RWMutex
+	lists map[primitive.ObjectID]TodoListDb
+}
+
+func (h *TodoListMemoryDbHandler) InsertOne(context context.Context, new *TodoListDb) (primitive.ObjectID, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.lists == nil {
+		h.lists = map[primitive.ObjectID]TodoListDb{}
+	}
+
+	list := *new
+	list.Owner = ownerOf(context, list.Owner)
+	if list.Id.IsZero() {
+		list.Id = primitive.NewObjectID()
+	}
+	h.lists[list.Id] = list
+
+	return list.Id, nil
+}
+
+func (h *TodoListMemoryDbHandler) FindOneById(context context.Context, id primitive.ObjectID) (*TodoListDb, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	list, ok := h.lists[id]
+	if !ok || ListRole(context, &list) == "" {
+		return nil, nil
+	}
+	return &list, nil
+}
+
+func (h *TodoListMemoryDbHandler) FindAll(context context.Context, archived bool, page PageOptions) (*TodoListPage, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	lists := []TodoListDb{}
+	for _, list := range h.lists {
+		if list.Archived == archived && ListRole(context, &list) != "" && (page.After == nil || listFollows(&list, page.After)) {
+			lists = append(lists, list)
+		}
+	}
+
+	slices.SortFunc(lists, compareLists)
+	if page.Limit > 0 && len(lists) > page.Limit+1 {
+		lists = lists[:page.Limit+1]
+	}
+
+	return newTodoListPage(lists, page), nil
+}
+
+// compareLists orders the lists by name and then by id, like the mongo handler
+func compareLists(a, b TodoListDb) int {
+	if c := strings.Compare(a.Name, b.Name); c != 0 {
+		return c
+	}
+	return bytes.Compare(a.Id[:], b.Id[:])
+}
+
+// listFollows tells whether the list sorts after the cursor
+func listFollows(list *TodoListDb, after *PageCursor) bool {
+	return compareLists(*list, TodoListDb{Id: after.Id, Name: after.Name}) > 0
+}
+
+func (h *TodoListMemoryDbHandler) UpdateOneById(context context.Context, id primitive.ObjectID, update *TodoListUpdate) (*TodoListDb, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	list, ok := h.lists[id]
+	if !ok || ListRole(context, &list) == "" {
+		return nil, nil
+	}
+
+	if update.Name != nil {
+		list.Name = *update.Name
+	}
+	if update.Description != nil {
+		list.Description = *update.Description
+	}
+	if update.Archived != nil {
+		list.Archived = *update.Archived
+	}
+	h.lists[id] = list
+
+	return &list, nil
+}
+
+func (h *TodoListMemoryDbHandler) DeleteOneById(context context.Context, id primitive.ObjectID) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if list, ok := h.lists[id]; ok && ListRole(context, &list) != "" {
+		delete(h.lists, id)
+	}
+	return nil
+}
+
+func (h *TodoListMemoryDbHandler) SetShare(context context.Context, id primitive.ObjectID, share Share) (*TodoListDb, error) {
+	return h.updateShares(context, id, share.User, &share)
+}
+
+func (h *TodoListMemoryDbHandler) RemoveShare(context context.Context, id primitive.ObjectID, user primitive.ObjectID) (*TodoListDb, error) {
+	return h.updateShares(context, id, user, nil)
+}
+
+func (h *TodoListMemoryDbHandler) updateShares(context context.Context, id primitive.ObjectID, user primitive.ObjectID, share *Share) (*TodoListDb, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	list, ok := h.lists[id]
+	if !ok || ListRole(context, &list) == "" {
+		return nil, nil
+	}
+
+	list.Shares = withShare(list.Shares, user, share)
+	h.lists[id] = list
+
+	return &list, nil
+}
+
+func (h *TodoListMemoryDbHandler) FindRoles(context context.Context) (map[primitive.ObjectID]string, error) {
+	if _, ok := OwnerFrom(context); !ok {
+		return map[primitive.ObjectID]string{}, nil
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	lists := []TodoListDb{}
+	for _, list := range h.lists {
+		if ListRole(context, &list) != "" {
+			lists = append(lists, list)
+		}
+	}
+
+	return listRoles(context, lists), nil
+}
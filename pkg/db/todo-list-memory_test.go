@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTodoListMemoryDbHandler(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Lists are filtered by archived and ordered by name", func(t *testing.T) {
+		ctx := context.Background()
+		h := TodoListMemoryDbHandler{}
+
+		work, _ := h.InsertOne(ctx, &TodoListDb{Name: "Work"})
+		home, _ := h.InsertOne(ctx, &TodoListDb{Name: "Home"})
+		old, _ := h.InsertOne(ctx, &TodoListDb{Name: "Old", Archived: true})
+
+		page, err := h.FindAll(ctx, false, PageOptions{})
+		if err != nil {
+			t.Fatalf("TodoListMemoryDbHandler.FindAll() error = %v, wantErr %v", err, false)
+		}
+		lists := page.Lists
+		if got := []string{lists[0].Id.Hex(), lists[1].Id.Hex()}; len(lists) != 2 || !reflect.DeepEqual(got, []string{home.Hex(), work.Hex()}) {
+			t.Errorf("TodoListMemoryDbHandler.FindAll() = %v, want Home and Work", lists)
+		}
+
+		page, _ = h.FindAll(ctx, true, PageOptions{})
+		if len(page.Lists) != 1 || page.Lists[0].Id != old {
+			t.Errorf("TodoListMemoryDbHandler.FindAll() archived = %v, want Old", page.Lists)
+		}
+	})
+
+	t.Run("Lists are paged by name", func(t *testing.T) {
+		ctx := context.Background()
+		h := TodoListMemoryDbHandler{}
+
+		work, _ := h.InsertOne(ctx, &TodoListDb{Name: "Work"})
+		home, _ := h.InsertOne(ctx, &TodoListDb{Name: "Home"})
+
+		page, err := h.FindAll(ctx, false, PageOptions{Limit: 1})
+		if err != nil {
+			t.Fatalf("TodoListMemoryDbHandler.FindAll() error = %v, wantErr %v", err, false)
+		}
+		if len(page.Lists) != 1 || page.Lists[0].Id != home || !page.Truncated || page.Next == nil {
+			t.Fatalf("TodoListMemoryDbHandler.FindAll() = %+v, want Home and a next page", page)
+		}
+
+		page, _ = h.FindAll(ctx, false, PageOptions{Limit: 1, After: page.Next})
+		if len(page.Lists) != 1 || page.Lists[0].Id != work || page.Truncated {
+			t.Errorf("TodoListMemoryDbHandler.FindAll() = %+v, want Work and no next page", page)
+		}
+	})
+
+	t.Run("Update only changes the given fields", func(t *testing.T) {
+		ctx := context.Background()
+		h := TodoListMemoryDbHandler{}
+
+		id, _ := h.InsertOne(ctx, &TodoListDb{Name: "Work", Description: "Desc"})
+
+		archived := true
+		list, err := h.UpdateOneById(ctx, id, &TodoListUpdate{Archived: &archived})
+		if err != nil {
+			t.Fatalf("TodoListMemoryDbHandler.UpdateOneById() error = %v, wantErr %v", err, false)
+		}
+
+		want := TodoListDb{Id: id, Name: "Work", Description: "Desc", Archived: true}
+		if !reflect.DeepEqual(*list, want) {
+			t.Errorf("TodoListMemoryDbHandler.UpdateOneById() = %v, want %v", *list, want)
+		}
+
+		h.DeleteOneById(ctx, id)
+		if list, _ := h.UpdateOneById(ctx, id, &TodoListUpdate{Archived: &archived}); list != nil {
+			t.Errorf("TodoListMemoryDbHandler.UpdateOneById() of a deleted list = %v, want nil", list)
+		}
+	})
+}
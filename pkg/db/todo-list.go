@@ -0,0 +1,241 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TodoListDbHandlerInterface stores the lists items can be grouped in, scoped to the lists the user stored in the context by WithOwner has a role on
+type TodoListDbHandlerInterface interface {
+	InsertOne(context.Context, *TodoListDb) (primitive.ObjectID, error)
+	// FindOneById returns the list, or nil when it doesn't exist
+	FindOneById(context.Context, primitive.ObjectID) (*TodoListDb, error)
+	// FindAll returns a page of the lists that are archived or not, ordered by name and id. The sort of the page is ignored.
+	FindAll(ctx context.Context, archived bool, page PageOptions) (*TodoListPage, error)
+	// UpdateOneById changes the fields set in the update and returns the updated list, or nil when it doesn't exist
+	UpdateOneById(context.Context, primitive.ObjectID, *TodoListUpdate) (*TodoListDb, error)
+	DeleteOneById(context.Context, primitive.ObjectID) error
+	// SetShare gives the user of the share its role on the list, and returns the updated list or nil when it doesn't exist
+	SetShare(context.Context, primitive.ObjectID, Share) (*TodoListDb, error)
+	// RemoveShare takes the role of the user on the list away, and returns the updated list or nil when it doesn't exist
+	RemoveShare(context.Context, primitive.ObjectID, primitive.ObjectID) (*TodoListDb, error)
+	// FindRoles returns the role of the user of the context on every list they own or that is shared with them
+	FindRoles(context.Context) (map[primitive.ObjectID]string, error)
+}
+
+type TodoListDb struct {
+	Id          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Owner       primitive.ObjectID `bson:"owner,omitempty" json:"owner"`
+	Name        string             `bson:"name" json:"name"`
+	Description string             `bson:"description" json:"description"`
+	// Archived lists are left out of the listings, and so are their items
+	Archived bool `bson:"archived,omitempty" json:"archived"`
+	// Shares give other users access to the list and its items
+	Shares []Share `bson:"shares,omitempty" json:"shares,omitempty"`
+}
+
+// TodoListPage is a single page of lists, when Truncated is set the following page starts after Next
+type TodoListPage struct {
+	Lists     []TodoListDb
+	Next      *PageCursor
+	Truncated bool
+}
+
+// listSort is the order of the lists, it tells list tokens apart from item tokens
+var listSort = []SortField{{Field: "name"}}
+
+// newTodoListPage builds a page out of lists that were fetched with one list more than the limit,
+// that extra list only tells whether there is a following page and is not returned
+func newTodoListPage(lists []TodoListDb, page PageOptions) *TodoListPage {
+	result := &TodoListPage{Lists: lists}
+	if page.Limit > 0 && len(lists) > page.Limit {
+		last := lists[page.Limit-1]
+		result.Lists = lists[:page.Limit]
+		result.Next = &PageCursor{Sort: formatSort(listSort), Id: last.Id, Name: last.Name}
+		result.Truncated = true
+	}
+
+	return result
+}
+
+// TodoListUpdate lists the fields of a list to change, nil fields are left untouched
+type TodoListUpdate struct {
+	Name        *string
+	Description *string
+	Archived    *bool
+}
+
+func (u *TodoListUpdate) bson() bson.M {
+	set := bson.M{}
+	unset := bson.M{}
+
+	if u.Name != nil {
+		set["name"] = *u.Name
+	}
+	if u.Description != nil {
+		set["description"] = *u.Description
+	}
+	if u.Archived != nil {
+		if *u.Archived {
+			set["archived"] = true
+		} else {
+			unset["archived"] = ""
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update
+}
+
+// DefaultListsCollection is the collection of the lists when TodoListDbHandler.Collection is empty
+const DefaultListsCollection = "lists"
+
+// TodoListDbHandler is the mongo backed implementation of TodoListDbHandlerInterface
+type TodoListDbHandler struct {
+	// Collection is the name of the collection the lists are stored in, DefaultListsCollection when empty
+	Collection string
+
+	coll *mongo.Collection
+}
+
+func (h *TodoListDbHandler) New(context context.Context, database *mongo.Database) error {
+	h.coll = database.Collection(orDefault(h.Collection, DefaultListsCollection))
+
+	_, err := h.coll.Indexes().CreateMany(context, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "owner", Value: 1}, {Key: "archived", Value: 1}, {Key: "name", Value: 1}}},
+		{Keys: bson.D{{Key: "shares.user", Value: 1}}},
+	})
+	return err
+}
+
+func (h *TodoListDbHandler) InsertOne(context context.Context, new *TodoListDb) (primitive.ObjectID, error) {
+	list := *new
+	list.Owner = ownerOf(context, list.Owner)
+
+	result, err := h.coll.InsertOne(context, &list)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (h *TodoListDbHandler) FindOneById(context context.Context, id primitive.ObjectID) (*TodoListDb, error) {
+	var list TodoListDb
+	err := h.coll.FindOne(context, visibleLists(context, bson.D{{Key: "_id", Value: id}})).Decode(&list)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+func (h *TodoListDbHandler) FindAll(context context.Context, archived bool, page PageOptions) (*TodoListPage, error) {
+	conditions := bson.D{{Key: "archived", Value: bson.M{"$ne": true}}}
+	if archived {
+		conditions = bson.D{{Key: "archived", Value: true}}
+	}
+	if page.After != nil {
+		conditions = append(conditions, bson.E{Key: "$or", Value: bson.A{
+			bson.M{"name": bson.M{"$gt": page.After.Name}},
+			bson.M{"name": page.After.Name, "_id": bson.M{"$gt": page.After.Id}},
+		}})
+	}
+	filter := visibleLists(context, conditions)
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}})
+	if page.Limit > 0 {
+		opts.SetLimit(int64(page.Limit + 1))
+	}
+	cur, err := h.coll.Find(context, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []TodoListDb{}
+	if err := cur.All(context, &lists); err != nil {
+		return nil, err
+	}
+
+	return newTodoListPage(lists, page), nil
+}
+
+func (h *TodoListDbHandler) UpdateOneById(context context.Context, id primitive.ObjectID, update *TodoListUpdate) (*TodoListDb, error) {
+	changes := update.bson()
+	if len(changes) == 0 {
+		return h.FindOneById(context, id)
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var list TodoListDb
+	err := h.coll.FindOneAndUpdate(context, visibleLists(context, bson.D{{Key: "_id", Value: id}}), changes, opts).Decode(&list)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+func (h *TodoListDbHandler) DeleteOneById(context context.Context, id primitive.ObjectID) error {
+	_, err := h.coll.DeleteOne(context, visibleLists(context, bson.D{{Key: "_id", Value: id}}))
+	return err
+}
+
+func (h *TodoListDbHandler) SetShare(context context.Context, id primitive.ObjectID, share Share) (*TodoListDb, error) {
+	return h.updateShares(context, id, shareUpdate(share))
+}
+
+func (h *TodoListDbHandler) RemoveShare(context context.Context, id primitive.ObjectID, user primitive.ObjectID) (*TodoListDb, error) {
+	return h.updateShares(context, id, unshareUpdate(user))
+}
+
+func (h *TodoListDbHandler) updateShares(context context.Context, id primitive.ObjectID, update any) (*TodoListDb, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var list TodoListDb
+	err := h.coll.FindOneAndUpdate(context, visibleLists(context, bson.D{{Key: "_id", Value: id}}), update, opts).Decode(&list)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+func (h *TodoListDbHandler) FindRoles(context context.Context) (map[primitive.ObjectID]string, error) {
+	if _, ok := OwnerFrom(context); !ok {
+		return map[primitive.ObjectID]string{}, nil
+	}
+
+	opts := options.Find().SetProjection(bson.M{"owner": 1, "shares": 1})
+	cur, err := h.coll.Find(context, visibleLists(context, bson.D{}), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []TodoListDb{}
+	if err := cur.All(context, &lists); err != nil {
+		return nil, err
+	}
+
+	return listRoles(context, lists), nil
+}
@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TodoItemTracing wraps a TodoItemDbHandlerInterface and records a span for every call, as a child of the span of the context
+type TodoItemTracing struct {
+	TodoItemDbHandlerInterface
+	Tracer trace.Tracer
+}
+
+// start starts the span of a call of the operation, the returned context makes the mongo commands of the call its children
+func (t *TodoItemTracing) start(context context.Context, operation string) (context.Context, trace.Span) {
+	return t.Tracer.Start(context, "TodoItemDbHandler."+operation, trace.WithAttributes(attribute.String("code.function", operation)))
+}
+
+// finish ends the span of a call, marking it as failed when the call failed
+func finish(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *TodoItemTracing) InsertOne(context context.Context, new *TodoItemDb) (id primitive.ObjectID, err error) {
+	context, span := t.start(context, "InsertOne")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.InsertOne(context, new)
+}
+
+func (t *TodoItemTracing) FindOneById(context context.Context, id primitive.ObjectID) (item *TodoItemDb, err error) {
+	context, span := t.start(context, "FindOneById")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.FindOneById(context, id)
+}
+
+func (t *TodoItemTracing) FindAll(context context.Context, filter TodoItemFilter, page PageOptions) (items *TodoItemPage, err error) {
+	context, span := t.start(context, "FindAll")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.FindAll(context, filter, page)
+}
+
+func (t *TodoItemTracing) Count(context context.Context, filter TodoItemFilter) (count int64, err error) {
+	context, span := t.start(context, "Count")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.Count(context, filter)
+}
+
+func (t *TodoItemTracing) AddLabels(context context.Context, id primitive.ObjectID, labels ...string) (item *TodoItemDb, err error) {
+	context, span := t.start(context, "AddLabels")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.AddLabels(context, id, labels...)
+}
+
+func (t *TodoItemTracing) AddLabelsToMany(context context.Context, ids []primitive.ObjectID, labels ...string) (err error) {
+	context, span := t.start(context, "AddLabelsToMany")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.AddLabelsToMany(context, ids, labels...)
+}
+
+func (t *TodoItemTracing) MoveToList(context context.Context, ids []primitive.ObjectID, listId primitive.ObjectID) (err error) {
+	context, span := t.start(context, "MoveToList")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.MoveToList(context, ids, listId)
+}
+
+func (t *TodoItemTracing) RemoveLabel(context context.Context, id primitive.ObjectID, label string) (item *TodoItemDb, err error) {
+	context, span := t.start(context, "RemoveLabel")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.RemoveLabel(context, id, label)
+}
+
+func (t *TodoItemTracing) TrashOneById(context context.Context, id primitive.ObjectID, version int64) (item *TodoItemDb, err error) {
+	context, span := t.start(context, "TrashOneById")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.TrashOneById(context, id, version)
+}
+
+func (t *TodoItemTracing) UntrashOneById(context context.Context, id primitive.ObjectID, version int64) (item *TodoItemDb, err error) {
+	context, span := t.start(context, "UntrashOneById")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.UntrashOneById(context, id, version)
+}
+
+func (t *TodoItemTracing) DeleteOneById(context context.Context, id primitive.ObjectID, version int64) (err error) {
+	context, span := t.start(context, "DeleteOneById")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.DeleteOneById(context, id, version)
+}
+
+func (t *TodoItemTracing) UpdateOneById(context context.Context, id primitive.ObjectID, update *TodoItemDb, version int64) (item *TodoItemDb, err error) {
+	context, span := t.start(context, "UpdateOneById")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.UpdateOneById(context, id, update, version)
+}
+
+func (t *TodoItemTracing) PatchOneById(context context.Context, id primitive.ObjectID, update *TodoItemUpdate, version int64) (item *TodoItemDb, err error) {
+	context, span := t.start(context, "PatchOneById")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.PatchOneById(context, id, update, version)
+}
+
+func (t *TodoItemTracing) SetShare(context context.Context, id primitive.ObjectID, share Share) (item *TodoItemDb, err error) {
+	context, span := t.start(context, "SetShare")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.SetShare(context, id, share)
+}
+
+func (t *TodoItemTracing) RemoveShare(context context.Context, id primitive.ObjectID, user primitive.ObjectID) (item *TodoItemDb, err error) {
+	context, span := t.start(context, "RemoveShare")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.RemoveShare(context, id, user)
+}
+
+func (t *TodoItemTracing) CountChildren(context context.Context, ids []primitive.ObjectID) (counts map[primitive.ObjectID]ChildCount, err error) {
+	context, span := t.start(context, "CountChildren")
+	defer func() { finish(span, err) }()
+	return t.TodoItemDbHandlerInterface.CountChildren(context, ids)
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTodoItemTracing(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+	h := &TodoItemTracing{TodoItemDbHandlerInterface: &TodoItemMemoryDbHandler{}, Tracer: tracer}
+
+	ctx, request := tracer.Start(context.Background(), "request")
+	id, _ := h.InsertOne(ctx, &TodoItemDb{Title: "Test_Title"})
+	h.DeleteOneById(ctx, id, 42)
+	request.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("TodoItemTracing recorded %d spans, want %d", len(spans), 3)
+	}
+	if spans[0].Name() != "TodoItemDbHandler.InsertOne" || spans[0].Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Errorf("TodoItemTracing span = %s, want InsertOne as a child of the request", spans[0].Name())
+	}
+	if spans[1].Name() != "TodoItemDbHandler.DeleteOneById" || spans[1].Status().Code != codes.Error || spans[1].Status().Description != ErrVersionConflict.Error() {
+		t.Errorf("TodoItemTracing span = %s %+v, want a failed DeleteOneById", spans[1].Name(), spans[1].Status())
+	}
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// trashState restricts a write to items in the trash, or to the ones that aren't
+type trashState int
+
+const (
+	notTrashed trashState = iota
+	trashed
+	anyTrashState
+)
+
+// filter adds the condition on the deletedAt field to a mongo filter
+func (s trashState) filter(filter bson.D) bson.D {
+	switch s {
+	case notTrashed:
+		return append(filter, bson.E{Key: "deletedAt", Value: bson.M{"$exists": false}})
+	case trashed:
+		return append(filter, bson.E{Key: "deletedAt", Value: bson.M{"$exists": true}})
+	}
+	return filter
+}
+
+func (s trashState) matches(item *TodoItemDb) bool {
+	switch s {
+	case notTrashed:
+		return item.DeletedAt == nil
+	case trashed:
+		return item.DeletedAt != nil
+	}
+	return true
+}
+
+// trashedAt is the deletion time stored for an item moved to the trash, with the precision mongo keeps
+func trashedAt() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
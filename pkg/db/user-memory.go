@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserMemoryDbHandler is an in-process implementation of UserDbHandlerInterface. The zero value is ready to use.
+type UserMemoryDbHandler struct {
+	mu    sync.RWMutex
+	users map[primitive.ObjectID]UserDb
+}
+
+func (h *UserMemoryDbHandler) InsertOne(context context.Context, new *UserDb) (primitive.ObjectID, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.users == nil {
+		h.users = map[primitive.ObjectID]UserDb{}
+	}
+
+	user := *new
+	if user.Id.IsZero() {
+		user.Id = primitive.NewObjectID()
+	}
+	h.users[user.Id] = user
+
+	return user.Id, nil
+}
+
+func (h *UserMemoryDbHandler) FindOneById(context context.Context, id primitive.ObjectID) (*UserDb, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	user, ok := h.users[id]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+func (h *UserMemoryDbHandler) UpsertBySubject(context context.Context, new *UserDb) (*UserDb, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, user := range h.users {
+		if user.Subject == new.Subject {
+			return &user, nil
+		}
+	}
+
+	if h.users == nil {
+		h.users = map[primitive.ObjectID]UserDb{}
+	}
+
+	user := *new
+	user.Id = primitive.NewObjectID()
+	h.users[user.Id] = user
+
+	return &user, nil
+}
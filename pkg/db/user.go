@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserDbHandlerInterface stores the users that own items and lists
+type UserDbHandlerInterface interface {
+	InsertOne(context.Context, *UserDb) (primitive.ObjectID, error)
+	// FindOneById returns the user, or nil when it doesn't exist
+	FindOneById(context.Context, primitive.ObjectID) (*UserDb, error)
+	// UpsertBySubject returns the user with the subject of new, inserting new when there is none yet
+	UpsertBySubject(context.Context, *UserDb) (*UserDb, error)
+}
+
+type UserDb struct {
+	Id   primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name string             `bson:"name" json:"name"`
+	// Subject is the sub claim of the tokens of the user, when they authenticate with tokens
+	Subject   string    `bson:"subject,omitempty" json:"subject,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// DefaultUsersCollection is the collection of the users when UserDbHandler.Collection is empty
+const DefaultUsersCollection = "users"
+
+// UserDbHandler is the mongo backed implementation of UserDbHandlerInterface
+type UserDbHandler struct {
+	// Collection is the name of the collection the users are stored in, DefaultUsersCollection when empty
+	Collection string
+
+	coll *mongo.Collection
+}
+
+func (h *UserDbHandler) New(context context.Context, database *mongo.Database) error {
+	h.coll = database.Collection(orDefault(h.Collection, DefaultUsersCollection))
+
+	// users without a subject are left out, so any number of them can exist
+	_, err := h.coll.Indexes().CreateOne(context, mongo.IndexModel{
+		Keys:    bson.D{{Key: "subject", Value: 1}},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"subject": bson.M{"$exists": true}}),
+	})
+	return err
+}
+
+func (h *UserDbHandler) InsertOne(context context.Context, new *UserDb) (primitive.ObjectID, error) {
+	result, err := h.coll.InsertOne(context, new)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func (h *UserDbHandler) FindOneById(context context.Context, id primitive.ObjectID) (*UserDb, error) {
+	var user UserDb
+	err := h.coll.FindOne(context, bson.D{{Key: "_id", Value: id}}).Decode(&user)
+
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (h *UserDbHandler) UpsertBySubject(context context.Context, new *UserDb) (*UserDb, error) {
+	update := bson.M{"$setOnInsert": bson.M{"name": new.Name, "createdAt": new.CreatedAt}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var user UserDb
+	err := h.coll.FindOneAndUpdate(context, bson.D{{Key: "subject", Value: new.Subject}}, update, opts).Decode(&user)
+	if mongo.IsDuplicateKeyError(err) {
+		// another request created the user of the subject at the same time, which the update finds now
+		err = h.coll.FindOneAndUpdate(context, bson.D{{Key: "subject", Value: new.Subject}}, update, opts).Decode(&user)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
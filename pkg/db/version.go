@@ -0,0 +1,33 @@
+package db
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AnyVersion skips the version check of updates and deletes
+const AnyVersion int64 = -1
+
+// ErrVersionConflict is returned when an update or delete expected another version of the item
+var ErrVersionConflict = errors.New("the item has been modified in the meantime")
+
+// versionFilter matches the item only in the expected version, which makes the check atomic with the write
+func versionFilter(id primitive.ObjectID, version int64) bson.D {
+	filter := bson.D{{Key: "_id", Value: id}}
+	switch version {
+	case AnyVersion:
+	case 0:
+		// items stored before versioning was introduced have no version field
+		filter = append(filter, bson.E{Key: "version", Value: bson.M{"$in": bson.A{0, nil}}})
+	default:
+		filter = append(filter, bson.E{Key: "version", Value: version})
+	}
+
+	return filter
+}
+
+func matchesVersion(item *TodoItemDb, version int64) bool {
+	return version == AnyVersion || item.Version == version
+}
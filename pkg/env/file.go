@@ -0,0 +1,128 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secrets when printing the config
+const redacted = "REDACTED"
+
+// setting is a field of the config with the name of its environment variable
+type setting struct {
+	key   string
+	value reflect.Value
+	// redact is true to hide the value when printing it, or password to only hide the password of a url
+	redact string
+}
+
+// settings lists the fields of the config in order
+func (c *config) settings() []setting {
+	settings := []setting{}
+	value := reflect.ValueOf(c).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("env"), ",")
+		settings = append(settings, setting{key: key, value: value.Field(i), redact: field.Tag.Get("redact")})
+	}
+	return settings
+}
+
+// readFile reads the settings of a YAML or TOML config file. The keys are the names of the environment variables,
+// in upper or lower case, and the values single values like 5000, true or "5s".
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("%s: unknown config file format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	known := map[string]bool{}
+	for _, s := range (&config{}).settings() {
+		known[s.key] = true
+	}
+
+	keys := []string{}
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	settings := map[string]string{}
+	errs := []error{}
+	for _, key := range keys {
+		value, name := raw[key], strings.ToUpper(key)
+		switch value.(type) {
+		case nil:
+			continue
+		case map[string]any, []any:
+			errs = append(errs, fmt.Errorf("%s: %s must be a single value", path, key))
+			continue
+		}
+		if !known[name] {
+			errs = append(errs, fmt.Errorf("%s: unknown setting %s", path, key))
+			continue
+		}
+		settings[name] = fmt.Sprint(value)
+	}
+	return settings, errors.Join(errs...)
+}
+
+// Print writes the config as YAML, in the format of a config file, with the secrets redacted
+func (c *config) Print(w io.Writer) error {
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings() {
+		value := redact(fmt.Sprint(s.value.Interface()), s.redact)
+		document.Content = append(document.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: s.key},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: value},
+		)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	defer encoder.Close()
+	return encoder.Encode(document)
+}
+
+func redact(value, mode string) string {
+	if value == "" {
+		return value
+	}
+
+	switch mode {
+	case "true":
+		return redacted
+	case "password":
+		u, err := url.Parse(value)
+		if err != nil {
+			return redacted
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+		return u.String()
+	}
+	return value
+}
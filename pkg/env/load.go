@@ -1,20 +1,157 @@
 package env
 
-import "github.com/caarlos0/env/v10"
+import (
+	"log/slog"
+	"maps"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/caarlos0/env/v10"
+)
+
+// supported values of STORAGE_BACKEND
+const (
+	StorageBackendMongo  = "mongo"
+	StorageBackendMemory = "memory"
+)
+
+// supported values of RATE_LIMIT_STORE
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreMongo  = "mongo"
+)
+
+// supported values of LOG_FORMAT
+const (
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+)
+
+// supported values of TRACES_EXPORTER
+const (
+	TracesExporterNone   = "none"
+	TracesExporterOTLP   = "otlp"
+	TracesExporterStdout = "stdout"
+	TracesExporterFile   = "file"
+)
+
+// supported values of SUBTASK_DELETE, they match the SubtaskDelete constants of the controller
+const (
+	SubtaskDeleteTrash    = "trash"
+	SubtaskDeleteOrphan   = "orphan"
+	SubtaskDeleteRestrict = "restrict"
+)
+
+// supported values of AUTH_MODE
+const (
+	AuthModeHeader = "header"
+	AuthModeJWT    = "jwt"
+)
 
 type config struct {
-	MongoURL           string `env:"MONGO_URL" envDefault:"mongodb://localhost:27017"`
-	MongodPath         string `env:"MONGOD_PATH" envDefault:"mongod"`
-	UseMemoryMongo     bool   `env:"USE_MEMORY_MONGO" envDefault:"true"`
-	MaxReturnArraySize int    `env:"MAX_RETURN_ARRAY_SIZE" envDefault:"100"`
-	Port               int    `env:"PORT"  envDefault:"5000"`
+	StorageBackend string `env:"STORAGE_BACKEND" envDefault:"mongo"`
+	MongoURL       string `env:"MONGO_URL" envDefault:"mongodb://localhost:27017" redact:"password"`
+	MongodPath     string `env:"MONGOD_PATH" envDefault:"mongod"`
+	UseMemoryMongo bool   `env:"USE_MEMORY_MONGO" envDefault:"true"`
+	// the database and the collections the service stores its documents in
+	MongoDatabase        string `env:"MONGO_DATABASE" envDefault:"ArticleManagement"`
+	ItemsCollection      string `env:"MONGO_ITEMS_COLLECTION" envDefault:"articles"`
+	RevisionsCollection  string `env:"MONGO_REVISIONS_COLLECTION" envDefault:"articles_history"`
+	ListsCollection      string `env:"MONGO_LISTS_COLLECTION" envDefault:"lists"`
+	UsersCollection      string `env:"MONGO_USERS_COLLECTION" envDefault:"users"`
+	ApiKeysCollection    string `env:"MONGO_API_KEYS_COLLECTION" envDefault:"api_keys"`
+	RateLimitsCollection string `env:"MONGO_RATE_LIMITS_COLLECTION" envDefault:"rate_limits"`
+	MigrationsCollection string `env:"MONGO_MIGRATIONS_COLLECTION" envDefault:"migrations"`
+	// MIGRATE_ON_STARTUP applies the missing schema migrations before the server starts, they can also be run by the migrate command
+	MigrateOnStartup bool `env:"MIGRATE_ON_STARTUP" envDefault:"true"`
+	// MIGRATION_STALE_AFTER is how long the startup waits for a migration another instance is applying, older claims are
+	// taken for runs that stopped halfway
+	MigrationStaleAfter time.Duration `env:"MIGRATION_STALE_AFTER" envDefault:"10m"`
+	MaxReturnArraySize  int           `env:"MAX_RETURN_ARRAY_SIZE" envDefault:"100"`
+	Port                int           `env:"PORT"  envDefault:"5000"`
+	// items in the trash are permanently deleted after TRASH_RETENTION, checked every TRASH_PURGE_INTERVAL
+	TrashRetention     time.Duration `env:"TRASH_RETENTION" envDefault:"720h"`
+	TrashPurgeInterval time.Duration `env:"TRASH_PURGE_INTERVAL" envDefault:"1h"`
+	// SUBTASK_DELETE is one of trash, orphan or restrict
+	SubtaskDelete string `env:"SUBTASK_DELETE" envDefault:"trash"`
+	// AUTH_MODE is jwt to require bearer tokens, or header to identify users by the X-User-Id header,
+	// which anyone can set, so it needs ALLOW_INSECURE_HEADER_AUTH and is only meant for development
+	AuthMode        string `env:"AUTH_MODE" envDefault:"jwt"`
+	AllowHeaderAuth bool   `env:"ALLOW_INSECURE_HEADER_AUTH" envDefault:"false"`
+	// the keys tokens can be signed with, at least one of them is needed for the jwt auth mode
+	JWTSecret        string `env:"JWT_HS256_SECRET" redact:"true"`
+	JWTPublicKeyFile string `env:"JWT_RS256_PUBLIC_KEY_FILE"`
+	JWKSFile         string `env:"JWT_JWKS_FILE"`
+	// the iss and aud claims tokens need to have, not checked when empty
+	JWTIssuer   string        `env:"JWT_ISSUER"`
+	JWTAudience string        `env:"JWT_AUDIENCE"`
+	JWTLeeway   time.Duration `env:"JWT_LEEWAY" envDefault:"30s"`
+	// the requests each client can make per RATE_LIMIT_WINDOW, reads are GET and HEAD requests, 0 disables the limit
+	RateLimitReads  int           `env:"RATE_LIMIT_READS" envDefault:"600"`
+	RateLimitWrites int           `env:"RATE_LIMIT_WRITES" envDefault:"120"`
+	RateLimitWindow time.Duration `env:"RATE_LIMIT_WINDOW" envDefault:"1m"`
+	// RATE_LIMIT_IP is the requests each IP can make per RATE_LIMIT_WINDOW before it is authenticated, 0 disables the limit
+	RateLimitIP int `env:"RATE_LIMIT_IP" envDefault:"1200"`
+	// RATE_LIMIT_STORE is memory to limit each replica on its own, or mongo to share the limits between replicas
+	RateLimitStore string `env:"RATE_LIMIT_STORE" envDefault:"memory"`
+	// LOG_LEVEL is one of debug, info, warn or error, debug includes every mongo command
+	LogLevel  slog.Level `env:"LOG_LEVEL" envDefault:"info"`
+	LogFormat string     `env:"LOG_FORMAT" envDefault:"json"`
+	// TRACES_EXPORTER is none, otlp to send the spans to the collector at OTEL_EXPORTER_OTLP_ENDPOINT,
+	// stdout or file to write them as JSON to stdout or TRACES_FILE
+	TracesExporter string        `env:"TRACES_EXPORTER" envDefault:"none"`
+	OTLPEndpoint   string        `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"http://localhost:4318"`
+	TracesFile     string        `env:"TRACES_FILE" envDefault:"traces.jsonl"`
+	TracesInterval time.Duration `env:"TRACES_EXPORT_INTERVAL" envDefault:"5s"`
+	TracesService  string        `env:"OTEL_SERVICE_NAME" envDefault:"todo-list-service"`
+	// TRACES_SAMPLE_RATIO is the share of the traces started by the service that are exported, traces of callers keep their decision
+	TracesSampleRatio float64 `env:"TRACES_SAMPLE_RATIO" envDefault:"1"`
+	// METRICS_ITEMS_REFRESH is how long the item counts of the metrics are kept before a scrape counts them again
+	MetricsItemsRefresh time.Duration `env:"METRICS_ITEMS_REFRESH" envDefault:"1m"`
+	// HEALTH_CHECK_TIMEOUT bounds each dependency check of the readiness probe
+	HealthCheckTimeout time.Duration `env:"HEALTH_CHECK_TIMEOUT" envDefault:"2s"`
+	// the timeouts of the http server for reading a request, writing its response and keeping idle connections open
+	ReadTimeout  time.Duration `env:"HTTP_READ_TIMEOUT" envDefault:"15s"`
+	WriteTimeout time.Duration `env:"HTTP_WRITE_TIMEOUT" envDefault:"30s"`
+	IdleTimeout  time.Duration `env:"HTTP_IDLE_TIMEOUT" envDefault:"2m"`
+	// on SIGINT or SIGTERM the readiness probe fails for SHUTDOWN_DELAY before the server stops accepting connections,
+	// then the requests in flight have SHUTDOWN_TIMEOUT to finish
+	ShutdownDelay   time.Duration `env:"SHUTDOWN_DELAY" envDefault:"5s"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"30s"`
 }
 
+// ConfigFileEnv names the config file Load reads, when it is set
+const ConfigFileEnv = "CONFIG_FILE"
+
+// Load reads the config from the environment, on top of the settings of the config file of CONFIG_FILE
 func Load() (*config, error) {
+	return LoadFile(os.Getenv(ConfigFileEnv))
+}
+
+// LoadFile reads the config from the YAML or TOML file, the environment overrides its settings. An empty path only reads the environment.
+// The config is validated, the error lists every invalid setting.
+func LoadFile(path string) (*config, error) {
+	environment := map[string]string{}
+	if path != "" {
+		settings, err := readFile(path)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(environment, settings)
+	}
+	for _, variable := range os.Environ() {
+		key, value, _ := strings.Cut(variable, "=")
+		environment[key] = value
+	}
+
 	cfg := config{}
-	if err := env.Parse(&cfg); err != nil {
+	if err := env.ParseWithOptions(&cfg, env.Options{Environment: environment}); err != nil {
 		return nil, err
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	return &cfg, nil
 }
@@ -0,0 +1,151 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Validate checks every setting, the error lists all of the invalid ones
+func (c *config) Validate() error {
+	errs := []error{}
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s %s", key, fmt.Sprintf(format, args...)))
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		if !slices.Contains(allowed, value) {
+			invalid(key, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+		}
+	}
+	positive := func(key string, value time.Duration) {
+		if value <= 0 {
+			invalid(key, "must be a positive duration, got %s", value)
+		}
+	}
+	notNegative := func(key string, value time.Duration) {
+		if value < 0 {
+			invalid(key, "must not be negative, got %s", value)
+		}
+	}
+
+	oneOf("STORAGE_BACKEND", c.StorageBackend, StorageBackendMongo, StorageBackendMemory)
+	if c.StorageBackend == StorageBackendMongo && !c.UseMemoryMongo {
+		if u, err := url.Parse(c.MongoURL); err != nil || (u.Scheme != "mongodb" && u.Scheme != "mongodb+srv") {
+			invalid("MONGO_URL", "must be a mongodb:// or mongodb+srv:// url")
+		}
+	}
+	if err := databaseName(c.MongoDatabase); err != nil {
+		invalid("MONGO_DATABASE", "%v", err)
+	}
+	// every collection needs a valid name of its own
+	collections := []struct{ key, name string }{
+		{"MONGO_ITEMS_COLLECTION", c.ItemsCollection},
+		{"MONGO_REVISIONS_COLLECTION", c.RevisionsCollection},
+		{"MONGO_LISTS_COLLECTION", c.ListsCollection},
+		{"MONGO_USERS_COLLECTION", c.UsersCollection},
+		{"MONGO_API_KEYS_COLLECTION", c.ApiKeysCollection},
+		{"MONGO_RATE_LIMITS_COLLECTION", c.RateLimitsCollection},
+		{"MONGO_MIGRATIONS_COLLECTION", c.MigrationsCollection},
+	}
+	used := map[string]string{}
+	for _, collection := range collections {
+		if err := collectionName(collection.name); err != nil {
+			invalid(collection.key, "%v", err)
+		} else if other, ok := used[collection.name]; ok {
+			invalid(collection.key, "must differ from %s, got %q", other, collection.name)
+		}
+		used[collection.name] = collection.key
+	}
+
+	if c.MaxReturnArraySize <= 0 {
+		invalid("MAX_RETURN_ARRAY_SIZE", "must be greater than 0, got %d", c.MaxReturnArraySize)
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		invalid("PORT", "must be between 1 and 65535, got %d", c.Port)
+	}
+	positive("TRASH_RETENTION", c.TrashRetention)
+	positive("TRASH_PURGE_INTERVAL", c.TrashPurgeInterval)
+	oneOf("SUBTASK_DELETE", c.SubtaskDelete, SubtaskDeleteTrash, SubtaskDeleteOrphan, SubtaskDeleteRestrict)
+
+	oneOf("AUTH_MODE", c.AuthMode, AuthModeHeader, AuthModeJWT)
+	if c.AuthMode == AuthModeHeader && !c.AllowHeaderAuth {
+		invalid("AUTH_MODE", "header lets any client act as any user and needs ALLOW_INSECURE_HEADER_AUTH=true, use it for development only")
+	}
+	notNegative("JWT_LEEWAY", c.JWTLeeway)
+
+	if c.RateLimitReads < 0 {
+		invalid("RATE_LIMIT_READS", "must not be negative, got %d", c.RateLimitReads)
+	}
+	if c.RateLimitWrites < 0 {
+		invalid("RATE_LIMIT_WRITES", "must not be negative, got %d", c.RateLimitWrites)
+	}
+	if c.RateLimitIP < 0 {
+		invalid("RATE_LIMIT_IP", "must not be negative, got %d", c.RateLimitIP)
+	}
+	positive("RATE_LIMIT_WINDOW", c.RateLimitWindow)
+	oneOf("RATE_LIMIT_STORE", c.RateLimitStore, RateLimitStoreMemory, RateLimitStoreMongo)
+	if c.RateLimitStore == RateLimitStoreMongo && c.StorageBackend != StorageBackendMongo {
+		invalid("RATE_LIMIT_STORE", "mongo needs the mongo storage backend")
+	}
+
+	oneOf("LOG_FORMAT", c.LogFormat, LogFormatJSON, LogFormatText)
+
+	oneOf("TRACES_EXPORTER", c.TracesExporter, TracesExporterNone, TracesExporterOTLP, TracesExporterStdout, TracesExporterFile)
+	if c.TracesExporter == TracesExporterOTLP {
+		if u, err := url.Parse(c.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("OTEL_EXPORTER_OTLP_ENDPOINT", "must be an http:// or https:// url, got %q", c.OTLPEndpoint)
+		}
+	}
+	if c.TracesExporter == TracesExporterFile && c.TracesFile == "" {
+		invalid("TRACES_FILE", "must be set for the file exporter")
+	}
+	positive("TRACES_EXPORT_INTERVAL", c.TracesInterval)
+	if c.TracesSampleRatio < 0 || c.TracesSampleRatio > 1 {
+		invalid("TRACES_SAMPLE_RATIO", "must be between 0 and 1, got %v", c.TracesSampleRatio)
+	}
+
+	notNegative("METRICS_ITEMS_REFRESH", c.MetricsItemsRefresh)
+	positive("MIGRATION_STALE_AFTER", c.MigrationStaleAfter)
+	positive("HEALTH_CHECK_TIMEOUT", c.HealthCheckTimeout)
+	notNegative("HTTP_READ_TIMEOUT", c.ReadTimeout)
+	notNegative("HTTP_WRITE_TIMEOUT", c.WriteTimeout)
+	notNegative("HTTP_IDLE_TIMEOUT", c.IdleTimeout)
+	notNegative("SHUTDOWN_DELAY", c.ShutdownDelay)
+	positive("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// databaseName checks the restrictions mongo has on the names of databases
+func databaseName(name string) error {
+	if name == "" {
+		return errors.New("must not be empty")
+	}
+	if len(name) >= 64 {
+		return fmt.Errorf("must be shorter than 64 characters, got %d", len(name))
+	}
+	if strings.ContainsAny(name, "/\\. \"$*<>:|?\x00") {
+		return fmt.Errorf("must not contain any of /\\. \"$*<>:|?, got %q", name)
+	}
+	return nil
+}
+
+// collectionName checks the restrictions mongo has on the names of collections
+func collectionName(name string) error {
+	if name == "" {
+		return errors.New("must not be empty")
+	}
+	if strings.ContainsAny(name, "$\x00") {
+		return fmt.Errorf("must not contain $, got %q", name)
+	}
+	if strings.HasPrefix(name, "system.") {
+		return fmt.Errorf("must not start with system., got %q", name)
+	}
+	return nil
+}
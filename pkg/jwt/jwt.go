@@ -0,0 +1,234 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// supported signing algorithms
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+var (
+	ErrMalformed    = errors.New("malformed token")
+	ErrUnknownKey   = errors.New("no key to verify the token")
+	ErrSignature    = errors.New("invalid signature")
+	ErrExpired      = errors.New("the token is expired")
+	ErrNotYetValid  = errors.New("the token is not valid yet")
+	ErrInvalidClaim = errors.New("invalid claim")
+)
+
+// Claims are the claims of a verified token, Raw holds all of them including the registered ones
+type Claims struct {
+	Subject   string
+	Issuer    string
+	Audience  []string
+	ExpiresAt time.Time
+	NotBefore time.Time
+	Raw       map[string]any
+}
+
+// Verifier checks the signature and the registered claims of tokens
+type Verifier struct {
+	Keys []Key
+	// Issuer and Audience are only checked when they are set
+	Issuer   string
+	Audience string
+	// Leeway is the clock skew allowed when checking exp and nbf
+	Leeway time.Duration
+	// Now defaults to time.Now
+	Now func() time.Time
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify returns the claims of the token when it is signed by one of the keys and its claims are valid.
+// Tokens have to expire, tokens without exp are rejected.
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	if err := v.verifySignature(&h, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	raw := map[string]any{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, err
+	}
+
+	claims, err := newClaims(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, v.checkClaims(claims)
+}
+
+// verifySignature tries the keys for the algorithm of the header, a kid in the header only tries the key with that id
+func (v *Verifier) verifySignature(h *header, signed string, signature []byte) error {
+	if h.Alg != HS256 && h.Alg != RS256 {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrMalformed, h.Alg)
+	}
+
+	tried := false
+	for _, key := range v.Keys {
+		if key.alg() != h.Alg || (h.Kid != "" && key.Id != "" && key.Id != h.Kid) {
+			continue
+		}
+
+		tried = true
+		if key.verify(signed, signature) {
+			return nil
+		}
+	}
+
+	if !tried {
+		return ErrUnknownKey
+	}
+	return ErrSignature
+}
+
+func (v *Verifier) checkClaims(claims *Claims) error {
+	now := time.Now()
+	if v.Now != nil {
+		now = v.Now()
+	}
+
+	if claims.ExpiresAt.IsZero() {
+		return fmt.Errorf("%w: exp is required", ErrInvalidClaim)
+	}
+	if !now.Before(claims.ExpiresAt.Add(v.Leeway)) {
+		return ErrExpired
+	}
+	if now.Add(v.Leeway).Before(claims.NotBefore) {
+		return ErrNotYetValid
+	}
+
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: sub is required", ErrInvalidClaim)
+	}
+	if v.Issuer != "" && claims.Issuer != v.Issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidClaim, claims.Issuer)
+	}
+	if v.Audience != "" && !slices.Contains(claims.Audience, v.Audience) {
+		return fmt.Errorf("%w: the token isn't meant for %q", ErrInvalidClaim, v.Audience)
+	}
+
+	return nil
+}
+
+// newClaims reads the registered claims, aud may be a single string or an array of them
+func newClaims(raw map[string]any) (*Claims, error) {
+	claims := &Claims{Raw: raw}
+
+	var ok bool
+	if claims.Subject, ok = optional[string](raw, "sub"); !ok {
+		return nil, fmt.Errorf("%w: sub must be a string", ErrInvalidClaim)
+	}
+	if claims.Issuer, ok = optional[string](raw, "iss"); !ok {
+		return nil, fmt.Errorf("%w: iss must be a string", ErrInvalidClaim)
+	}
+
+	switch aud := raw["aud"].(type) {
+	case nil:
+	case string:
+		claims.Audience = []string{aud}
+	case []any:
+		for _, a := range aud {
+			s, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: aud must be a string or an array of strings", ErrInvalidClaim)
+			}
+			claims.Audience = append(claims.Audience, s)
+		}
+	default:
+		return nil, fmt.Errorf("%w: aud must be a string or an array of strings", ErrInvalidClaim)
+	}
+
+	for name, field := range map[string]*time.Time{"exp": &claims.ExpiresAt, "nbf": &claims.NotBefore} {
+		seconds, ok := optional[float64](raw, name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a number", ErrInvalidClaim, name)
+		}
+		if seconds != 0 {
+			*field = time.Unix(0, int64(seconds*float64(time.Second)))
+		}
+	}
+
+	return claims, nil
+}
+
+// optional returns the claim, or the zero value when it isn't there, ok is false when it has another type
+func optional[T any](raw map[string]any, name string) (value T, ok bool) {
+	claim, found := raw[name]
+	if !found {
+		return value, true
+	}
+
+	value, ok = claim.(T)
+	return value, ok
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformed
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	return nil
+}
+
+// Key is an HS256 secret or an RS256 public key, Id matches the kid of token headers
+type Key struct {
+	Id     string
+	Secret []byte
+	Public *rsa.PublicKey
+}
+
+func (k *Key) alg() string {
+	if k.Public != nil {
+		return RS256
+	}
+	return HS256
+}
+
+func (k *Key) verify(signed string, signature []byte) bool {
+	if k.Public != nil {
+		digest := sha256.Sum256([]byte(signed))
+		return rsa.VerifyPKCS1v15(k.Public, crypto.SHA256, digest[:], signature) == nil
+	}
+
+	mac := hmac.New(sha256.New, k.Secret)
+	mac.Write([]byte(signed))
+	return hmac.Equal(mac.Sum(nil), signature)
+}
@@ -0,0 +1,139 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func sign(t *testing.T, alg, kid string, key any, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims(changes map[string]any) map[string]any {
+	claims := map[string]any{
+		"sub": "user-1",
+		"iss": "https://issuer.example",
+		"aud": []string{"todo", "other"},
+		"exp": now.Add(time.Hour).Unix(),
+		"nbf": now.Add(-time.Hour).Unix(),
+	}
+	for name, value := range changes {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+	return claims
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("Test_Secret")
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	v := &Verifier{
+		Keys:     []Key{{Secret: secret}, {Id: "rsa-1", Public: &private.PublicKey}},
+		Issuer:   "https://issuer.example",
+		Audience: "todo",
+		Leeway:   time.Minute,
+		Now:      func() time.Time { return now },
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"HS256", sign(t, HS256, "", secret, validClaims(nil)), nil},
+		{"RS256", sign(t, RS256, "rsa-1", private, validClaims(nil)), nil},
+		{"Single audience", sign(t, HS256, "", secret, validClaims(map[string]any{"aud": "todo"})), nil},
+		{"Expired within leeway", sign(t, HS256, "", secret, validClaims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()})), nil},
+		{"Expired", sign(t, HS256, "", secret, validClaims(map[string]any{"exp": now.Add(-time.Hour).Unix()})), ErrExpired},
+		{"Without exp", sign(t, HS256, "", secret, validClaims(map[string]any{"exp": nil})), ErrInvalidClaim},
+		{"Not yet valid", sign(t, HS256, "", secret, validClaims(map[string]any{"nbf": now.Add(time.Hour).Unix()})), ErrNotYetValid},
+		{"Wrong issuer", sign(t, HS256, "", secret, validClaims(map[string]any{"iss": "https://evil.example"})), ErrInvalidClaim},
+		{"Wrong audience", sign(t, HS256, "", secret, validClaims(map[string]any{"aud": "other"})), ErrInvalidClaim},
+		{"Without subject", sign(t, HS256, "", secret, validClaims(map[string]any{"sub": nil})), ErrInvalidClaim},
+		{"Wrong secret", sign(t, HS256, "", []byte("Other_Secret"), validClaims(nil)), ErrSignature},
+		{"Wrong RSA key", sign(t, RS256, "rsa-1", other, validClaims(nil)), ErrSignature},
+		{"Unknown kid", sign(t, RS256, "rsa-2", private, validClaims(nil)), ErrUnknownKey},
+		{"Unsupported algorithm", sign(t, "none", "", nil, validClaims(nil)), ErrMalformed},
+		{"Not a token", "not.a-token", ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verifier.Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (claims.Subject != "user-1" || claims.Raw["iss"] != "https://issuer.example") {
+				t.Errorf("Verifier.Verify() = %+v", claims)
+			}
+		})
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	t.Parallel()
+
+	private, _ := rsa.GenerateKey(rand.Reader, 2048)
+	n := base64.RawURLEncoding.EncodeToString(private.N.Bytes())
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa-1", "use": "sig", "alg": "RS256", "n": %q, "e": "AQAB"},
+		{"kty": "RSA", "kid": "rsa-enc", "use": "enc", "n": %q, "e": "AQAB"},
+		{"kty": "oct", "kid": "hmac-1", "k": "VGVzdF9TZWNyZXQ"},
+		{"kty": "EC", "kid": "ec-1"}
+	]}`, n, n)
+
+	keys, err := ParseJWKS([]byte(jwks))
+	if err != nil {
+		t.Fatalf("ParseJWKS() error = %v", err)
+	}
+	if len(keys) != 2 || keys[0].Id != "rsa-1" || !keys[0].Public.Equal(&private.PublicKey) || string(keys[1].Secret) != "Test_Secret" {
+		t.Fatalf("ParseJWKS() = %+v", keys)
+	}
+
+	v := &Verifier{Keys: keys, Now: func() time.Time { return now }}
+	if _, err := v.Verify(sign(t, RS256, "rsa-1", private, validClaims(nil))); err != nil {
+		t.Errorf("Verifier.Verify() with a JWKS key error = %v", err)
+	}
+
+	if _, err := ParseJWKS([]byte(`{"keys": [{"kty": "RSA", "n": "", "e": "AQAB"}]}`)); err == nil {
+		t.Errorf("ParseJWKS() of a key without modulus error = %v, wantErr %v", err, true)
+	}
+}
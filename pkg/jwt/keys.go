@@ -0,0 +1,91 @@
+package jwt
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+)
+
+// ParseRSAPublicKey reads a PEM encoded RSA public key, either PKIX ("PUBLIC KEY") or PKCS #1 ("RSA PUBLIC KEY")
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	public, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("not an RSA public key")
+	}
+	return public, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// ParseJWKS reads the keys of a JSON Web Key Set, RSA and symmetric ("oct") keys are supported.
+// Keys meant for encryption or for other algorithms are skipped.
+func ParseJWKS(data []byte) ([]Key, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := []Key{}
+	for i, k := range set.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+
+		switch k.Kty {
+		case "oct":
+			if k.Alg != "" && k.Alg != HS256 {
+				continue
+			}
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return nil, fmt.Errorf("key %d: invalid k", i)
+			}
+			keys = append(keys, Key{Id: k.Kid, Secret: secret})
+
+		case "RSA":
+			if k.Alg != "" && k.Alg != RS256 {
+				continue
+			}
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil || len(n) == 0 {
+				return nil, fmt.Errorf("key %d: invalid n", i)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil || len(e) == 0 || len(e) > 4 {
+				return nil, fmt.Errorf("key %d: invalid e", i)
+			}
+			public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			keys = append(keys, Key{Id: k.Kid, Public: public})
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,54 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Adds the request id of the context", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := New(&out, slog.LevelInfo, true).With("component", "test")
+
+		logger.InfoContext(WithRequestId(context.Background(), "abc"), "request")
+
+		var record map[string]any
+		if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+			t.Fatalf("New() logged %q, want JSON: %v", out.String(), err)
+		}
+		if record["request_id"] != "abc" || record["component"] != "test" || record["msg"] != "request" {
+			t.Errorf("New() logged %v, want the message with the request id and attributes", record)
+		}
+	})
+
+	t.Run("Adds the trace of the context", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := New(&out, slog.LevelInfo, true)
+
+		span := trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})
+		logger.InfoContext(trace.ContextWithSpanContext(context.Background(), span), "request")
+
+		var record map[string]any
+		json.Unmarshal(out.Bytes(), &record)
+		if record["trace_id"] != span.TraceID().String() || record["span_id"] != span.SpanID().String() {
+			t.Errorf("New() logged %v, want the trace and span ids", record)
+		}
+	})
+
+	t.Run("Leaves out records below the level", func(t *testing.T) {
+		var out bytes.Buffer
+		logger := New(&out, slog.LevelWarn, false)
+
+		logger.Info("hidden")
+		if out.Len() != 0 {
+			t.Errorf("New() logged %q below the level", out.String())
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLog logs every request once it is done, with the route template, status and latency.
+// Server errors are logged at error level and client errors as warnings, along with the type and detail of their problem.
+// It has to come before the ErrorHandler to see the problem.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if value, ok := c.Get(problemKey); ok {
+			problem := value.(*Problem)
+			attrs = append(attrs, slog.String("problem_type", strings.TrimPrefix(problem.Type, ProblemTypeBase)))
+			if problem.Detail != "" {
+				attrs = append(attrs, slog.String("detail", problem.Detail))
+			}
+		}
+
+		slog.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
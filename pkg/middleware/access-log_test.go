@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// not parallel, it replaces the default logger
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&out, nil)))
+	defer slog.SetDefault(defaultLogger)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.AccessLog(), middleware.ErrorHandler())
+	engine.GET("/conflict", func(c *gin.Context) {
+		c.AbortWithError(http.StatusConflict, fmt.Errorf("the list is archived"))
+	})
+	engine.GET("/fail", func(c *gin.Context) {
+		c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("connection refused"))
+	})
+
+	do := func(path string) []map[string]any {
+		out.Reset()
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+
+		records := []map[string]any{}
+		for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+			var record map[string]any
+			json.Unmarshal([]byte(line), &record)
+			records = append(records, record)
+		}
+		return records
+	}
+
+	t.Run("Logs client errors once with their problem", func(t *testing.T) {
+		records := do("/conflict")
+		if len(records) != 1 {
+			t.Fatalf("AccessLog() logged %v, want a single record", records)
+		}
+		if record := records[0]; record["msg"] != "request" || record["level"] != "WARN" || record["problem_type"] != "conflict" || record["detail"] != "the list is archived" {
+			t.Errorf("AccessLog() logged %v, want the request with the problem", record)
+		}
+	})
+
+	t.Run("Logs the cause of server errors separately", func(t *testing.T) {
+		records := do("/fail")
+		if len(records) != 2 || records[0]["error"] != "connection refused" || records[1]["problem_type"] != "internal" {
+			t.Errorf("AccessLog() logged %v, want the cause and the request", records)
+		}
+	})
+}
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Actor stores who is making the request in the request context, so changes can be attributed to them.
+// The engine needs ContextWithFallback enabled for handlers passing the gin context on to read it.
+func Actor() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(db.WithActor(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// how often the last use of a key is written, at most
+const apiKeyTouchInterval = time.Minute
+
+// ApiKey identifies the calling user by an "Authorization: ApiKey <key>" header and scopes the request to that user,
+// the key is stored as "apiKey" and its scope as "scope" in the gin context. Requests with another Authorization header
+// are left to the next middlewares, read-only keys are rejected with a 403 on anything but reads.
+func ApiKey(keys db.ApiKeyDbHandlerInterface, users db.UserDbHandlerInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, secret, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "ApiKey") {
+			c.Next()
+			return
+		}
+
+		key, err := keys.FindOneByHash(c, db.HashApiKey(strings.TrimSpace(secret)))
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		if key == nil {
+			c.Header("WWW-Authenticate", fmt.Sprintf("ApiKey realm=%q", realm))
+			c.AbortWithError(http.StatusUnauthorized, fmt.Errorf("unknown api key"))
+			return
+		}
+
+		if key.Scope == db.ApiKeyScopeRead && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.AbortWithError(http.StatusForbidden, fmt.Errorf("the api key is read-only"))
+			return
+		}
+
+		user, err := users.FindOneById(c, key.Owner)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		if user == nil {
+			c.AbortWithError(http.StatusUnauthorized, fmt.Errorf("the owner of the api key doesn't exist anymore"))
+			return
+		}
+
+		now := time.Now().UTC().Truncate(time.Millisecond)
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+			if err := keys.Touch(c, key.Id, now); err != nil {
+				c.AbortWithError(http.StatusInternalServerError, err)
+				return
+			}
+		}
+
+		scopeToUser(c, user)
+		c.Set("apiKey", key)
+		c.Set("scope", key.Scope)
+		c.Next()
+	}
+}
+
+// identified tells whether an earlier middleware already identified the calling user
+func identified(c *gin.Context) bool {
+	_, ok := c.Get("user")
+	return ok
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"todo-list-service/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the realm of the WWW-Authenticate challenges
+const realm = "todo-list-service"
+
+// JWT only lets requests with a valid bearer token through, and stores the sub claim as "subject"
+// and the claims as "claims" in the gin context. Other requests are rejected with a 401 and a WWW-Authenticate challenge,
+// unless an earlier middleware identified the user.
+func JWT(verifier *jwt.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identified(c) {
+			c.Next()
+			return
+		}
+
+		scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", realm))
+			c.AbortWithError(http.StatusUnauthorized, fmt.Errorf("a bearer token is required"))
+			return
+		}
+
+		claims, err := verifier.Verify(strings.TrimSpace(token))
+		if err != nil {
+			c.Header("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q, error=\"invalid_token\", error_description=%q", realm, err.Error()))
+			c.AbortWithError(http.StatusUnauthorized, err)
+			return
+		}
+
+		c.Set("subject", claims.Subject)
+		c.Set("claims", claims)
+		c.Next()
+	}
+}
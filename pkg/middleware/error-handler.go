@@ -1,28 +1,81 @@
 package middleware
 
 import (
-	"log"
+	"encoding/json"
+	"log/slog"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-// simple error middleware that returns the occurred errors to the user and logs them
-// FIXME: consider not exposing internal errors to the user to mitigate security risk
+// problemKey stores the problem the ErrorHandler responded with in the gin context, for the AccessLog
+const problemKey = "problem"
+
+// ErrorHandler responds to failed requests with an application/problem+json body, see Problem.
+// Requests aborted with a status of 400 or more get one even without an error, unless the handler wrote a body itself.
+// The errors of client errors are shown as detail, the ones of server errors are only logged, at error level.
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		writer := &deferredWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
 		c.Next()
 
-		clientIp := c.ClientIP()
-		var errors []string
-		for _, err := range c.Errors {
-			errS := err.Error()
-			errors = append(errors, errS)
-			log.Printf(`Error: "%s" occurred in url "%s" by IP "%s"`, errS, c.Request.URL, clientIp)
+		// client errors end up in the access log with their problem, the causes of server errors are hidden from the client
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			for _, err := range c.Errors {
+				slog.ErrorContext(c.Request.Context(), "request failed", "error", err.Error(), "path", c.Request.URL.Path, "client_ip", c.ClientIP())
+			}
 		}
 
-		if len(errors) > 0 {
-			// status -1 doesn't overwrite existing status code
-			c.JSON(-1, gin.H{"errors": errors})
+		if status >= http.StatusBadRequest && !writer.Written() {
+			problem := newProblem(c, status, c.Errors)
+			c.Set(problemKey, problem)
+			c.Render(-1, problemRender{problem})
 		}
+		writer.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+// deferredWriter holds the status line back until the body is written or the request is done,
+// so the problem of aborted requests can still set its content type
+type deferredWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *deferredWriter) WriteHeaderNow() {}
+
+func (w *deferredWriter) Write(data []byte) (int, error) {
+	w.ResponseWriter.WriteHeaderNow()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *deferredWriter) WriteString(s string) (int, error) {
+	w.ResponseWriter.WriteHeaderNow()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Written only counts the body, the status line is always deferred
+func (w *deferredWriter) Written() bool {
+	return w.ResponseWriter.Size() > 0
+}
+
+// problemRender renders a problem as JSON with the problem content type
+type problemRender struct {
+	problem *Problem
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	body, err := json.Marshal(r.problem)
+	if err != nil {
+		return err
 	}
+
+	_, err = w.Write(body)
+	return err
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", ProblemContentType)
 }
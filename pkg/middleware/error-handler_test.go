@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorHandler(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.RequestId(), middleware.ErrorHandler())
+	engine.POST("/items", func(c *gin.Context) {
+		body := &struct {
+			Title string   `json:"title" binding:"required"`
+			Ids   []string `json:"ids" binding:"required,dive,mongodb"`
+		}{}
+		if err := c.ShouldBindJSON(body); err != nil {
+			c.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		c.Status(http.StatusCreated)
+	})
+	engine.GET("/items/:id", func(c *gin.Context) { c.AbortWithStatus(http.StatusNotFound) })
+	engine.GET("/fail", func(c *gin.Context) {
+		c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("connection to 10.0.0.1 refused"))
+	})
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, middleware.Problem) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(middleware.RequestIdHeader, "test-request")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		var problem middleware.Problem
+		json.Unmarshal(w.Body.Bytes(), &problem)
+		return w, problem
+	}
+
+	t.Run("Describes invalid fields", func(t *testing.T) {
+		w, problem := do(http.MethodPost, "/items", `{"ids": ["nope"]}`)
+		if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != middleware.ProblemContentType {
+			t.Fatalf("POST /items = %d %q, want %d %q", w.Code, w.Header().Get("Content-Type"), http.StatusBadRequest, middleware.ProblemContentType)
+		}
+
+		want := []middleware.FieldError{{Field: "title", Reason: "is required"}, {Field: "ids[0]", Reason: "must be an object id"}}
+		if problem.Type != middleware.ProblemTypeBase+"validation" || problem.Status != http.StatusBadRequest || !reflect.DeepEqual(problem.Errors, want) {
+			t.Errorf("POST /items problem = %+v, want the validation errors %+v", problem, want)
+		}
+	})
+
+	t.Run("Describes aborted requests without an error", func(t *testing.T) {
+		w, problem := do(http.MethodGet, "/items/1", "")
+		want := middleware.Problem{
+			Type:      middleware.ProblemTypeBase + "not-found",
+			Title:     "Not Found",
+			Status:    http.StatusNotFound,
+			Instance:  "/items/1",
+			RequestId: "test-request",
+		}
+		if w.Code != http.StatusNotFound || !reflect.DeepEqual(problem, want) {
+			t.Errorf("GET /items/:id = %d %+v, want %+v", w.Code, problem, want)
+		}
+	})
+
+	t.Run("Hides the details of server errors", func(t *testing.T) {
+		w, problem := do(http.MethodGet, "/fail", "")
+		if w.Code != http.StatusInternalServerError || problem.Type != middleware.ProblemTypeBase+"internal" || strings.Contains(w.Body.String(), "10.0.0.1") {
+			t.Errorf("GET /fail = %d %s, want an internal problem without the error", w.Code, w.Body)
+		}
+	})
+
+	t.Run("Leaves successful responses alone", func(t *testing.T) {
+		w, _ := do(http.MethodPost, "/items", `{"title": "Title", "ids": ["65b000000000000000000001"]}`)
+		if w.Code != http.StatusCreated || w.Body.Len() != 0 || w.Header().Get(middleware.RequestIdHeader) != "test-request" {
+			t.Errorf("POST /items = %d %q with request id %q, want an empty 201", w.Code, w.Body, w.Header().Get(middleware.RequestIdHeader))
+		}
+	})
+}
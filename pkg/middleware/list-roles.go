@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListRoles loads the roles of the calling user on their own and shared lists, which extend to the items of those lists.
+// It has to come after the middleware identifying the user.
+func ListRoles(lists db.TodoListDbHandlerInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles, err := lists.FindRoles(c)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		c.Request = c.Request.WithContext(db.WithListRoles(c.Request.Context(), roles))
+		c.Next()
+	}
+}
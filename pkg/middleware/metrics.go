@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// the route label of requests that didn't match a route, so unknown paths don't create new series
+const unmatchedRoute = "unmatched"
+
+// Metrics counts the requests and records their latency by method, route template and status, and tracks the requests in flight
+func Metrics(registerer prometheus.Registerer) gin.HandlerFunc {
+	factory := promauto.With(registerer)
+	requests := factory.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled.",
+	}, []string{"method", "route", "status"})
+	latency := factory.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of HTTP requests.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+	inFlight := factory.NewGauge(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests being handled.",
+	})
+
+	return func(c *gin.Context) {
+		start := time.Now()
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		latency.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,46 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	registry := prometheus.NewRegistry()
+	engine := gin.New()
+	engine.Use(middleware.Metrics(registry))
+	engine.GET("/metrics", gin.WrapH(promhttp.HandlerFor(registry, promhttp.HandlerOpts{})))
+	engine.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	for _, path := range []string{"/items/1", "/items/2", "/unknown"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain") {
+		t.Errorf("GET /metrics Content-Type = %q, want the text format", contentType)
+	}
+
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/items/:id",status="404"} 2`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="GET",route="/items/:id",status="404"} 2`,
+		// the scrape itself is in flight
+		`http_requests_in_flight 1`,
+	} {
+		if !strings.Contains(w.Body.String(), want+"\n") {
+			t.Errorf("GET /metrics = %s, want a line %s", w.Body.String(), want)
+		}
+	}
+}
@@ -35,3 +35,16 @@ func LabelParam() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+func UserIdParam() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userIdString := c.Param("userId")
+		if userIdString == "" {
+			c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("faulty redirect; no userId param found"))
+			return
+		}
+
+		c.Set("userId", userIdString)
+		c.Next()
+	}
+}
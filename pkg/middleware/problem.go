@@ -0,0 +1,181 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the content type of error responses, see RFC 7807
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBase prefixes the type of every problem, the rest names the category of the error
+const ProblemTypeBase = "urn:todo-list-service:problem:"
+
+// the categories of problems that aren't derived from the status code
+const (
+	problemValidation = "validation"
+	problemInternal   = "internal"
+)
+
+// the categories of client errors by status code, other client errors are a "client-error"
+var problemCategories = map[int]string{
+	http.StatusBadRequest:           "bad-request",
+	http.StatusUnauthorized:         "unauthorized",
+	http.StatusForbidden:            "forbidden",
+	http.StatusNotFound:             "not-found",
+	http.StatusMethodNotAllowed:     "method-not-allowed",
+	http.StatusConflict:             "conflict",
+	http.StatusPreconditionFailed:   "precondition-failed",
+	http.StatusUnsupportedMediaType: "unsupported-media-type",
+	http.StatusUnprocessableEntity:  "unprocessable",
+	http.StatusTooManyRequests:      "rate-limited",
+}
+
+// Problem is the body of every error response
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	RequestId string `json:"requestId,omitempty"`
+	// Errors lists the invalid fields of validation problems
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is an invalid field of the request body, named by its JSON path
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func init() {
+	// names fields in validation errors the way clients send them
+	if validate, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return field.Name
+			}
+			return name
+		})
+	}
+}
+
+// newProblem describes the errors of a request that failed with the status, the details of server errors are left out
+func newProblem(c *gin.Context, status int, errs []*gin.Error) *Problem {
+	problem := &Problem{
+		Title:     http.StatusText(status),
+		Status:    status,
+		Instance:  c.Request.URL.Path,
+		RequestId: c.GetString("requestId"),
+	}
+
+	if status >= http.StatusInternalServerError {
+		problem.Type = ProblemTypeBase + problemInternal
+		problem.Detail = "an internal error occurred, please report it along with the request id"
+		return problem
+	}
+
+	category, ok := problemCategories[status]
+	if !ok {
+		category = "client-error"
+	}
+	problem.Type = ProblemTypeBase + category
+
+	details := []string{}
+	for _, err := range errs {
+		if fields := fieldErrors(err.Err); len(fields) > 0 {
+			problem.Errors = append(problem.Errors, fields...)
+			continue
+		}
+		details = append(details, err.Error())
+	}
+
+	if len(problem.Errors) > 0 {
+		problem.Type = ProblemTypeBase + problemValidation
+		problem.Title = "Validation failed"
+		details = append(details, invalidFields(len(problem.Errors)))
+	}
+	problem.Detail = strings.Join(details, "; ")
+
+	return problem
+}
+
+// fieldErrors extracts the invalid fields of binding and decoding errors
+func fieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := []FieldError{}
+		for _, fieldError := range validationErrors {
+			fields = append(fields, FieldError{Field: fieldPath(fieldError), Reason: fieldReason(fieldError)})
+		}
+		return fields
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return []FieldError{{Field: typeError.Field, Reason: fmt.Sprintf("must be a %s", jsonType(typeError.Type))}}
+	}
+
+	return nil
+}
+
+// fieldPath is the namespace of the field without the name of the body struct, like "labels[0]"
+func fieldPath(fieldError validator.FieldError) string {
+	_, path, found := strings.Cut(fieldError.Namespace(), ".")
+	if !found {
+		return fieldError.Field()
+	}
+	return path
+}
+
+func fieldReason(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required", "required_without":
+		return "is required"
+	case "mongodb":
+		return "must be an object id"
+	case "oneof":
+		return fmt.Sprintf("must be one of %s", strings.ReplaceAll(fieldError.Param(), " ", ", "))
+	case "min":
+		return fmt.Sprintf("must have at least %s elements", fieldError.Param())
+	}
+	return fmt.Sprintf("fails the %s check", fieldError.Tag())
+}
+
+// jsonType names the JSON type a Go type is decoded from
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		if t.String() == "time.Time" {
+			return "RFC 3339 date string"
+		}
+		return "object"
+	}
+	return "number"
+}
+
+func invalidFields(count int) string {
+	if count == 1 {
+		return "1 field of the request is invalid"
+	}
+	return fmt.Sprintf("%d fields of the request are invalid", count)
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"todo-list-service/pkg/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit limits the requests of each client with a token bucket, reads and writes have separate buckets and limits.
+// Clients are told apart by their api key, otherwise by the identified user and otherwise by their IP,
+// so it has to come after the middlewares identifying the user. A limit of 0 disables it.
+//
+// Responses carry the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers of the bucket,
+// requests without tokens left are rejected with a 429 and a Retry-After header.
+func RateLimit(buckets db.RateLimitDbHandlerInterface, reads db.RateLimit, writes db.RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, kind := writes, "write"
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			limit, kind = reads, "read"
+		}
+
+		if limit.Limit <= 0 {
+			c.Next()
+			return
+		}
+
+		take(c, buckets, rateLimitKey(c)+":"+kind, limit, kind+" requests")
+	}
+}
+
+// IPRateLimit limits the requests of each IP with a single token bucket. It comes before the middlewares identifying the user,
+// so requests the authentication rejects are limited as well, and RateLimit limits the identified clients after it.
+// A limit of 0 disables it.
+func IPRateLimit(buckets db.RateLimitDbHandlerInterface, limit db.RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit.Limit <= 0 {
+			c.Next()
+			return
+		}
+
+		take(c, buckets, "ip:"+c.ClientIP(), limit, "requests from this address")
+	}
+}
+
+// take takes a token from the bucket of the key and sets the headers, it aborts the request when there are no tokens left
+func take(c *gin.Context, buckets db.RateLimitDbHandlerInterface, key string, limit db.RateLimit, requests string) {
+	result, err := buckets.Take(c, key, limit, time.Now())
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(limit.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", seconds(result.Reset))
+	c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s", limit.Limit, seconds(limit.Window)))
+
+	if !result.Allowed {
+		c.Header("Retry-After", seconds(result.RetryAfter))
+		c.AbortWithError(http.StatusTooManyRequests, fmt.Errorf("too many %s, retry in %s seconds", requests, seconds(result.RetryAfter)))
+		return
+	}
+
+	c.Next()
+}
+
+// rateLimitKey tells the clients apart
+func rateLimitKey(c *gin.Context) string {
+	if key, ok := c.Get("apiKey"); ok {
+		return "key:" + key.(*db.ApiKeyDb).Id.Hex()
+	}
+	if user, ok := c.Get("user"); ok {
+		return "user:" + user.(*db.UserDb).Id.Hex()
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds formats the duration in whole seconds, rounding up so clients don't retry too early
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,89 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"todo-list-service/pkg/db"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.ErrorHandler(), middleware.RateLimit(
+		&db.RateLimitMemoryDbHandler{},
+		db.RateLimit{Limit: 2, Window: time.Minute},
+		db.RateLimit{Limit: 1, Window: time.Minute},
+	))
+	engine.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	engine.POST("/", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	do := func(method, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Limits reads and writes separately", func(t *testing.T) {
+		if w := do(http.MethodPost, "10.0.0.1"); w.Code != http.StatusCreated {
+			t.Errorf("POST / status = %d, want %d", w.Code, http.StatusCreated)
+		}
+		if w := do(http.MethodPost, "10.0.0.1"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+			t.Errorf("POST / over the limit = %d with Retry-After %q, want %d with 60", w.Code, w.Header().Get("Retry-After"), http.StatusTooManyRequests)
+		}
+
+		w := do(http.MethodGet, "10.0.0.1")
+		if w.Code != http.StatusOK {
+			t.Errorf("GET / after the writes status = %d, want %d", w.Code, http.StatusOK)
+		}
+		for header, want := range map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": "1", "RateLimit-Reset": "30", "RateLimit-Policy": "2;w=60"} {
+			if got := w.Header().Get(header); got != want {
+				t.Errorf("GET / %s = %q, want %q", header, got, want)
+			}
+		}
+	})
+
+	t.Run("Limits clients separately", func(t *testing.T) {
+		do(http.MethodPost, "10.0.0.2")
+		if w := do(http.MethodPost, "10.0.0.3"); w.Code != http.StatusCreated {
+			t.Errorf("POST / of another client status = %d, want %d", w.Code, http.StatusCreated)
+		}
+	})
+}
+
+func TestIPRateLimit(t *testing.T) {
+	t.Parallel()
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.ErrorHandler(), middleware.IPRateLimit(&db.RateLimitMemoryDbHandler{}, db.RateLimit{Limit: 2, Window: time.Minute}))
+	// stands in for an authentication rejecting every request
+	engine.GET("/", func(c *gin.Context) { c.AbortWithStatus(http.StatusUnauthorized) })
+
+	do := func(ip string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Limits requests that fail the authentication", func(t *testing.T) {
+		do("10.0.0.1")
+		do("10.0.0.1")
+		if code := do("10.0.0.1"); code != http.StatusTooManyRequests {
+			t.Errorf("GET / over the limit status = %d, want %d", code, http.StatusTooManyRequests)
+		}
+		if code := do("10.0.0.2"); code != http.StatusUnauthorized {
+			t.Errorf("GET / of another IP status = %d, want %d", code, http.StatusUnauthorized)
+		}
+	})
+}
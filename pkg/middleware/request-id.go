@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"todo-list-service/pkg/logging"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIdHeader carries the id of a request, callers can send their own to correlate requests across services
+const RequestIdHeader = "X-Request-ID"
+
+// RequestId stores the id of the request as "requestId" in the gin context and in the request context for the logs,
+// adds it to the span of the request and echoes it in the response.
+// Incoming ids are kept when they are at most 128 printable ASCII characters, otherwise a random id is generated.
+func RequestId() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIdHeader)
+		if !validRequestId(id) {
+			id = newRequestId()
+		}
+
+		c.Set("requestId", id)
+		c.Request = c.Request.WithContext(logging.WithRequestId(c.Request.Context(), id))
+		trace.SpanFromContext(c.Request.Context()).SetAttributes(attribute.String("request.id", id))
+		c.Header(RequestIdHeader, id)
+		c.Next()
+	}
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	random := make([]byte, 16)
+	rand.Read(random)
+	return hex.EncodeToString(random)
+}
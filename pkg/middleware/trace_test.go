@@ -0,0 +1,63 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.ContextWithFallback = true
+	engine.Use(otelgin.Middleware("test", otelgin.WithTracerProvider(provider), otelgin.WithPropagators(propagation.TraceContext{})))
+	engine.Use(middleware.RequestId())
+	engine.GET("/todo/:id", func(c *gin.Context) {
+		// the spans of the handler are children of the request span
+		_, span := provider.Tracer("test").Start(c, "handler")
+		span.End()
+		c.Status(http.StatusServiceUnavailable)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/todo/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(middleware.RequestIdHeader, "abc")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("Trace recorded %d spans, want %d", len(spans), 2)
+	}
+
+	handler, request := spans[0], spans[1]
+	if request.Name() != "/todo/:id" || request.Parent().SpanID().String() != "00f067aa0ba902b7" || request.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Trace request span = %s with parent %v, want a server span continuing the trace of the caller", request.Name(), request.Parent())
+	}
+	if request.Status().Code != codes.Error {
+		t.Errorf("Trace request span status = %+v, want a failed span", request.Status())
+	}
+	found := false
+	for _, attr := range request.Attributes() {
+		found = found || attr == attribute.String("request.id", "abc")
+	}
+	if !found {
+		t.Errorf("Trace request span attributes = %v, want the request id", request.Attributes())
+	}
+	if handler.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Errorf("Trace handler span parent = %v, want %v", handler.Parent().SpanID(), request.SpanContext().SpanID())
+	}
+}
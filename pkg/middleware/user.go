@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+	"todo-list-service/pkg/db"
+	"todo-list-service/pkg/jwt"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserHeader carries the id of the calling user
+const UserHeader = "X-User-Id"
+
+// User identifies the calling user by the UserHeader and scopes the request to the items and lists of that user.
+// Requests without a known user are rejected with a 401, unless an earlier middleware identified the user.
+func User(users db.UserDbHandlerInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identified(c) {
+			c.Next()
+			return
+		}
+
+		id, err := primitive.ObjectIDFromHex(c.GetHeader(UserHeader))
+		if err != nil {
+			c.AbortWithError(http.StatusUnauthorized, fmt.Errorf("the %s header must hold the id of a user", UserHeader))
+			return
+		}
+
+		user, err := users.FindOneById(c, id)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		if user == nil {
+			c.AbortWithError(http.StatusUnauthorized, fmt.Errorf("unknown user"))
+			return
+		}
+
+		scopeToUser(c, user)
+		c.Next()
+	}
+}
+
+// TokenUser identifies the calling user by the subject stored by JWT and scopes the request to the items and lists of that user.
+// Users are created the first time their subject shows up, named after the name claim when there is one.
+func TokenUser(users db.UserDbHandlerInterface) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identified(c) {
+			c.Next()
+			return
+		}
+
+		subject := c.GetString("subject")
+		if subject == "" {
+			c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("faulty setup; no subject found"))
+			return
+		}
+
+		name := subject
+		if claims, ok := c.Get("claims"); ok {
+			if claimed, ok := claims.(*jwt.Claims).Raw["name"].(string); ok && claimed != "" {
+				name = claimed
+			}
+		}
+
+		user, err := users.UpsertBySubject(c, &db.UserDb{
+			Subject:   subject,
+			Name:      name,
+			CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		})
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		scopeToUser(c, user)
+		c.Next()
+	}
+}
+
+// scopeToUser stores the user as "user" in the gin context, scopes the storage to the user and makes them the actor of the changes
+func scopeToUser(c *gin.Context, user *db.UserDb) {
+	ctx := db.WithOwner(c.Request.Context(), user.Id)
+	c.Request = c.Request.WithContext(db.WithActor(ctx, user.Id.Hex()))
+	c.Set("user", user)
+}
@@ -0,0 +1,381 @@
+package recurrence
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// supported values of FREQ
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// the formats UNTIL can be given in, a date only includes the whole day
+const (
+	untilDateTime = "20060102T150405Z"
+	untilDate     = "20060102"
+)
+
+// a rule that never produces an occurrence would loop forever, so expansion gives up after this many empty periods
+const maxEmptyPeriods = 1000
+
+// series with a COUNT have to be walked from their start, so expansion gives up when it doesn't reach the window within this many periods
+const maxPeriodsBeforeWindow = 10000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Weekday is a single BYDAY entry, Ordinal selects e.g. the first (1) or last (-1) weekday of the month, 0 means every one
+type Weekday struct {
+	Ordinal int
+	Day     time.Weekday
+}
+
+// Rule is the subset of an RFC 5545 RRULE that items can repeat with: FREQ, INTERVAL, BYDAY, COUNT and UNTIL.
+// The first occurrence of the series is its start, which is always included like the DTSTART of a calendar event.
+type Rule struct {
+	Freq     string
+	Interval int
+	ByDay    []Weekday
+	// Count is the number of occurrences including the start, 0 means no limit
+	Count int
+	// Until is the last time an occurrence can fall on
+	Until *time.Time
+}
+
+// Parse parses an RRULE like "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;COUNT=10", with or without the "RRULE:" prefix
+func Parse(rrule string) (*Rule, error) {
+	rule := &Rule{Interval: 1}
+	seen := map[string]bool{}
+
+	for _, part := range strings.Split(strings.TrimPrefix(rrule, "RRULE:"), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate rule part %s", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			if !slices.Contains([]string{Daily, Weekly, Monthly, Yearly}, value) {
+				return nil, fmt.Errorf("FREQ must be one of DAILY, WEEKLY, MONTHLY or YEARLY")
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			rule.Interval, err = positiveInt(key, value)
+		case "COUNT":
+			rule.Count, err = positiveInt(key, value)
+		case "UNTIL":
+			rule.Until, err = parseUntil(value)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(value)
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL can't be combined")
+	}
+	for _, day := range rule.ByDay {
+		if day.Ordinal != 0 && rule.Freq != Monthly {
+			return nil, fmt.Errorf("BYDAY ordinals are only supported with FREQ=MONTHLY")
+		}
+	}
+	if len(rule.ByDay) > 0 && rule.Freq == Yearly {
+		return nil, fmt.Errorf("BYDAY is not supported with FREQ=YEARLY")
+	}
+
+	return rule, nil
+}
+
+func positiveInt(key, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%s must be a positive integer", key)
+	}
+	return n, nil
+}
+
+func parseUntil(value string) (*time.Time, error) {
+	if until, err := time.Parse(untilDateTime, value); err == nil {
+		return &until, nil
+	}
+
+	if date, err := time.Parse(untilDate, value); err == nil {
+		until := date.AddDate(0, 0, 1).Add(-time.Second)
+		return &until, nil
+	}
+
+	return nil, fmt.Errorf("UNTIL must be a UTC date like 20301231 or 20301231T235959Z")
+}
+
+func parseByDay(value string) ([]Weekday, error) {
+	days := []Weekday{}
+	for _, entry := range strings.Split(value, ",") {
+		if len(entry) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY entry %q", entry)
+		}
+
+		day, ok := weekdays[entry[len(entry)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY entry %q", entry)
+		}
+
+		ordinal := 0
+		if prefix := entry[:len(entry)-2]; prefix != "" {
+			n, err := strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid BYDAY entry %q", entry)
+			}
+			ordinal = n
+		}
+
+		if !slices.Contains(days, Weekday{Ordinal: ordinal, Day: day}) {
+			days = append(days, Weekday{Ordinal: ordinal, Day: day})
+		}
+	}
+
+	return days, nil
+}
+
+// String formats the rule in a canonical form, so equal rules are stored the same way
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = strings.ToUpper(day.Day.String()[:2])
+			if day.Ordinal != 0 {
+				days[i] = strconv.Itoa(day.Ordinal) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilDateTime))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Next returns the occurrence that follows the one at start, along with the rule that continues the series from there.
+// ok is false when start is the last occurrence.
+func (r *Rule) Next(start time.Time) (next time.Time, rest *Rule, ok bool) {
+	r.each(start, start, func(i int, occurrence time.Time) bool {
+		if i == 0 {
+			return true
+		}
+
+		next, ok = occurrence, true
+		return false
+	})
+
+	if !ok {
+		return time.Time{}, nil, false
+	}
+
+	rest = &Rule{Freq: r.Freq, Interval: r.Interval, ByDay: slices.Clone(r.ByDay), Until: r.Until}
+	if r.Count > 0 {
+		rest.Count = r.Count - 1
+	}
+	return next, rest, true
+}
+
+// Between returns at most max occurrences of the series that starts at start, within [from, to).
+// It returns none when a series with a COUNT starts too many periods before from.
+func (r *Rule) Between(start, from, to time.Time, max int) []time.Time {
+	occurrences := []time.Time{}
+	r.each(start, from, func(_ int, occurrence time.Time) bool {
+		if !occurrence.Before(to) {
+			return false
+		}
+
+		if !occurrence.Before(from) {
+			occurrences = append(occurrences, occurrence)
+		}
+		return len(occurrences) < max
+	})
+
+	return occurrences
+}
+
+// each calls yield with the occurrences of the series in order, until yield returns false or the series ends.
+// Occurrences before from may be left out: series without a COUNT skip the periods before it, the others stop
+// when they don't reach it within maxPeriodsBeforeWindow periods. i is the index in the series when nothing was skipped.
+func (r *Rule) each(start, from time.Time, yield func(i int, occurrence time.Time) bool) {
+	i := 0
+	emit := func(occurrence time.Time) bool {
+		if r.Until != nil && occurrence.After(*r.Until) {
+			return false
+		}
+		if r.Count > 0 && i >= r.Count {
+			return false
+		}
+
+		i++
+		return yield(i-1, occurrence)
+	}
+
+	first := 0
+	if r.Count == 0 {
+		first = r.periodsBefore(start, from)
+	}
+	if first == 0 && !emit(start) {
+		return
+	}
+
+	empty := 0
+	for period := first; empty < maxEmptyPeriods; period++ {
+		candidates := r.period(start, period)
+		if len(candidates) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+
+		if period-first >= maxPeriodsBeforeWindow && candidates[len(candidates)-1].Before(from) {
+			return
+		}
+
+		for _, candidate := range candidates {
+			if !candidate.After(start) {
+				continue
+			}
+			if !emit(candidate) {
+				return
+			}
+		}
+	}
+}
+
+// periodsBefore is the number of periods after the start that can be skipped without missing an occurrence at or after from
+func (r *Rule) periodsBefore(start, from time.Time) int {
+	if !from.After(start) {
+		return 0
+	}
+
+	// Unix seconds don't overflow like durations do for dates that are centuries apart
+	var periods int
+	switch r.Freq {
+	case Daily:
+		periods = int((from.Unix() - start.Unix()) / (24 * 60 * 60))
+	case Weekly:
+		periods = int((from.Unix() - start.Unix()) / (7 * 24 * 60 * 60))
+	case Monthly:
+		periods = (from.Year()-start.Year())*12 + int(from.Month()) - int(start.Month())
+	case Yearly:
+		periods = from.Year() - start.Year()
+	}
+
+	// the period before the one from falls into may still have occurrences after it, like weeks with BYDAY do
+	return max(periods/r.Interval-1, 0)
+}
+
+// period returns the candidate occurrences of the n-th period after the start, in order
+func (r *Rule) period(start time.Time, n int) []time.Time {
+	steps := n * r.Interval
+	year, month, day := start.Date()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+	}
+
+	switch r.Freq {
+	case Daily:
+		date := at(year, month, day+steps)
+		if len(r.ByDay) > 0 && !slices.ContainsFunc(r.ByDay, func(d Weekday) bool { return d.Day == date.Weekday() }) {
+			return nil
+		}
+		return []time.Time{date}
+
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return []time.Time{at(year, month, day+7*steps)}
+		}
+
+		// weeks start on monday, like the default WKST
+		monday := day - (int(start.Weekday())+6)%7 + 7*steps
+		dates := []time.Time{}
+		for _, d := range r.ByDay {
+			dates = append(dates, at(year, month, monday+(int(d.Day)+6)%7))
+		}
+		slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+		return dates
+
+	case Monthly:
+		first := at(year, month+time.Month(steps), 1)
+		if len(r.ByDay) == 0 {
+			date := at(first.Year(), first.Month(), day)
+			// months that are too short are skipped, like RFC 5545 does
+			if date.Month() != first.Month() {
+				return nil
+			}
+			return []time.Time{date}
+		}
+		return r.weekdaysOfMonth(first)
+
+	case Yearly:
+		date := at(year+steps, month, day)
+		if date.Month() != month {
+			return nil
+		}
+		return []time.Time{date}
+	}
+
+	return nil
+}
+
+// weekdaysOfMonth returns the days of the month matched by BYDAY, in order
+func (r *Rule) weekdaysOfMonth(first time.Time) []time.Time {
+	byDay := map[time.Weekday][]time.Time{}
+	for date := first; date.Month() == first.Month(); date = date.AddDate(0, 0, 1) {
+		byDay[date.Weekday()] = append(byDay[date.Weekday()], date)
+	}
+
+	dates := []time.Time{}
+	for _, d := range r.ByDay {
+		all := byDay[d.Day]
+		switch {
+		case d.Ordinal == 0:
+			dates = append(dates, all...)
+		case d.Ordinal > 0 && d.Ordinal <= len(all):
+			dates = append(dates, all[d.Ordinal-1])
+		case d.Ordinal < 0 && -d.Ordinal <= len(all):
+			dates = append(dates, all[len(all)+d.Ordinal])
+		}
+	}
+
+	slices.SortFunc(dates, func(a, b time.Time) int { return a.Compare(b) })
+	return slices.CompactFunc(dates, func(a, b time.Time) bool { return a.Equal(b) })
+}
@@ -0,0 +1,143 @@
+package recurrence
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rrule   string
+		want    string
+		wantErr bool
+	}{
+		{"Daily", "FREQ=DAILY", "FREQ=DAILY", false},
+		{"Prefix and defaults", "RRULE:FREQ=WEEKLY;INTERVAL=1", "FREQ=WEEKLY", false},
+		{"All parts", "FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR;COUNT=5", "FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR;COUNT=5", false},
+		{"Until date", "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20301231", "FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20301231T235959Z", false},
+		{"Duplicate days", "FREQ=WEEKLY;BYDAY=MO,MO", "FREQ=WEEKLY;BYDAY=MO", false},
+		{"Missing FREQ", "INTERVAL=2", "", true},
+		{"Unknown FREQ", "FREQ=HOURLY", "", true},
+		{"Unsupported part", "FREQ=DAILY;BYHOUR=9", "", true},
+		{"Invalid interval", "FREQ=DAILY;INTERVAL=0", "", true},
+		{"Count and until", "FREQ=DAILY;COUNT=2;UNTIL=20301231", "", true},
+		{"Invalid day", "FREQ=WEEKLY;BYDAY=XX", "", true},
+		{"Ordinal outside monthly", "FREQ=WEEKLY;BYDAY=1MO", "", true},
+		{"Duplicate part", "FREQ=DAILY;FREQ=WEEKLY", "", true},
+		{"Empty", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.rrule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("Parse().String() = %v, want %v", got.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRule_Between(t *testing.T) {
+	tests := []struct {
+		name  string
+		rrule string
+		start string
+		want  []string
+	}{
+		{"Daily with interval", "FREQ=DAILY;INTERVAL=2", "2030-01-01T09:00:00Z",
+			[]string{"2030-01-01T09:00:00Z", "2030-01-03T09:00:00Z", "2030-01-05T09:00:00Z", "2030-01-07T09:00:00Z"}},
+		{"Weekdays", "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR;COUNT=3", "2030-01-04T09:00:00Z", // a friday
+			[]string{"2030-01-04T09:00:00Z", "2030-01-07T09:00:00Z", "2030-01-08T09:00:00Z"}},
+		{"Every other week on two days", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "2030-01-02T09:00:00Z", // a wednesday
+			[]string{"2030-01-02T09:00:00Z", "2030-01-14T09:00:00Z", "2030-01-16T09:00:00Z", "2030-01-28T09:00:00Z"}},
+		{"Start off the rule", "FREQ=WEEKLY;BYDAY=FR;COUNT=2", "2030-01-01T09:00:00Z",
+			[]string{"2030-01-01T09:00:00Z", "2030-01-04T09:00:00Z"}},
+		{"Skips short months", "FREQ=MONTHLY", "2030-01-31T09:00:00Z",
+			[]string{"2030-01-31T09:00:00Z", "2030-03-31T09:00:00Z"}},
+		{"Last friday of the month", "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", "2030-01-25T09:00:00Z",
+			[]string{"2030-01-25T09:00:00Z", "2030-02-22T09:00:00Z", "2030-03-29T09:00:00Z"}},
+		{"Leap day", "FREQ=YEARLY", "2028-02-29T09:00:00Z",
+			[]string{"2028-02-29T09:00:00Z"}},
+		{"Until", "FREQ=DAILY;UNTIL=20300102T090000Z", "2030-01-01T09:00:00Z",
+			[]string{"2030-01-01T09:00:00Z", "2030-01-02T09:00:00Z"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rrule)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			start := date(tt.start)
+			got := []string{}
+			for _, occurrence := range rule.Between(start, start, start.AddDate(0, 3, 0), 4) {
+				got = append(got, occurrence.Format(time.RFC3339))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rule.Between() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRule_Between_FarWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		rrule    string
+		start    string
+		from, to string
+		want     []string
+	}{
+		{"Skips to the window", "FREQ=DAILY", "0001-01-01T09:00:00Z", "9999-01-01T00:00:00Z", "9999-01-04T00:00:00Z",
+			[]string{"9999-01-01T09:00:00Z", "9999-01-02T09:00:00Z", "9999-01-03T09:00:00Z"}},
+		{"Keeps the weeks of the interval", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE", "2030-01-02T09:00:00Z", "2031-01-01T00:00:00Z", "2031-01-15T00:00:00Z",
+			[]string{"2031-01-01T09:00:00Z", "2031-01-13T09:00:00Z"}},
+		{"Skips short months", "FREQ=MONTHLY", "2030-01-31T09:00:00Z", "2040-02-01T00:00:00Z", "2040-05-01T00:00:00Z",
+			[]string{"2040-03-31T09:00:00Z"}},
+		{"Gives up on counted series far before the window", "FREQ=DAILY;COUNT=100000", "1900-01-01T09:00:00Z", "2030-01-01T00:00:00Z", "2030-01-02T00:00:00Z",
+			[]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rrule)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			got := []string{}
+			for _, occurrence := range rule.Between(date(tt.start), date(tt.from), date(tt.to), 10) {
+				got = append(got, occurrence.Format(time.RFC3339))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rule.Between() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRule_Next(t *testing.T) {
+	rule, _ := Parse("FREQ=WEEKLY;BYDAY=TU,TH;COUNT=2")
+	start := date("2030-01-01T09:00:00Z") // a tuesday
+
+	next, rest, ok := rule.Next(start)
+	if !ok || !next.Equal(date("2030-01-03T09:00:00Z")) || rest.String() != "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=1" {
+		t.Fatalf("Rule.Next() = %v, %v, %v", next, rest, ok)
+	}
+
+	if _, _, ok := rest.Next(next); ok {
+		t.Errorf("Rule.Next() of the last occurrence ok = %v, want %v", ok, false)
+	}
+}
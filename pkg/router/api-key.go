@@ -0,0 +1,17 @@
+package router
+
+import (
+	"todo-list-service/pkg/controller"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AttachApiKeyRoutes(engine gin.IRoutes, ctrl *controller.ApiKeyController) {
+	engine.GET("/api-keys", ctrl.FindAll)
+
+	engine.POST("/api-keys", ctrl.Create)
+	engine.POST("/api-keys/:id/rotate", middleware.IdParam(), ctrl.Rotate)
+
+	engine.DELETE("/api-keys/:id", middleware.IdParam(), ctrl.Revoke)
+}
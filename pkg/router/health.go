@@ -0,0 +1,14 @@
+package router
+
+import (
+	"todo-list-service/pkg/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AttachHealthRoutes exposes the probes, outside of the authenticated and rate limited routes
+func AttachHealthRoutes(engine gin.IRoutes, ctrl *controller.HealthController) {
+	engine.GET("/healthz", ctrl.Live)
+	engine.GET("/readyz", ctrl.Ready)
+	engine.GET("/health", ctrl.Health)
+}
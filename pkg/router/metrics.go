@@ -0,0 +1,12 @@
+package router
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// AttachMetricsRoutes exposes the metrics for Prometheus to scrape, outside of the authenticated routes
+func AttachMetricsRoutes(engine gin.IRoutes, gatherer prometheus.Gatherer) {
+	engine.GET("/metrics", gin.WrapH(promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})))
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"todo-list-service/pkg/controller"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AttachShareRoutes(engine gin.IRoutes, ctrl *controller.ShareController) {
+	engine.GET("/todo/:id/shares", middleware.IdParam(), ctrl.ItemShares)
+	engine.GET("/lists/:id/shares", middleware.IdParam(), ctrl.ListShares)
+
+	engine.POST("/todo/:id/shares", middleware.IdParam(), ctrl.ShareItem)
+	engine.POST("/lists/:id/shares", middleware.IdParam(), ctrl.ShareList)
+
+	engine.PUT("/todo/:id/shares/:userId", middleware.IdParam(), middleware.UserIdParam(), ctrl.ChangeItemRole)
+	engine.PUT("/lists/:id/shares/:userId", middleware.IdParam(), middleware.UserIdParam(), ctrl.ChangeListRole)
+
+	engine.DELETE("/todo/:id/shares/:userId", middleware.IdParam(), middleware.UserIdParam(), ctrl.UnshareItem)
+	engine.DELETE("/lists/:id/shares/:userId", middleware.IdParam(), middleware.UserIdParam(), ctrl.UnshareList)
+}
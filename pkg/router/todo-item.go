@@ -7,14 +7,30 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func AttachTodoItemRoutes(engine *gin.Engine, ctrl *controller.TodoItemController) {
+func AttachTodoItemRoutes(engine gin.IRoutes, ctrl *controller.TodoItemController) {
 	engine.GET("/todo", ctrl.FindAll)
+	engine.GET("/todo/plan", ctrl.Plan)
 	engine.GET("/todo/:id", middleware.IdParam(), ctrl.FindOneById)
+	engine.GET("/todo/:id/history", middleware.IdParam(), ctrl.History)
+	engine.GET("/todo/:id/children", middleware.IdParam(), ctrl.Children)
+	engine.GET("/todo/:id/subtree", middleware.IdParam(), ctrl.Subtree)
+	engine.GET("/todo/:id/blockers", middleware.IdParam(), ctrl.Blockers)
+	engine.GET("/todo/:id/dependents", middleware.IdParam(), ctrl.Dependents)
 	engine.GET("/todo/label/:label", middleware.LabelParam(), ctrl.FindByLabel)
 
 	engine.POST("/todo", ctrl.Create)
+	engine.POST("/todo/labels", ctrl.AddLabelsToMany)
+	engine.POST("/todo/:id/labels", middleware.IdParam(), ctrl.AddLabels)
+	engine.POST("/todo/:id/restore", middleware.IdParam(), ctrl.Restore)
 
 	engine.PUT("/todo/:id", middleware.IdParam(), ctrl.UpdateByID)
 
+	engine.PATCH("/todo/:id", middleware.IdParam(), ctrl.PatchByID)
+
 	engine.DELETE("/todo/:id", middleware.IdParam(), ctrl.DeleteOneById)
+	engine.DELETE("/todo/:id/labels/:label", middleware.IdParam(), middleware.LabelParam(), ctrl.RemoveLabel)
+
+	engine.GET("/trash", ctrl.FindTrash)
+	engine.POST("/trash/:id/restore", middleware.IdParam(), ctrl.RestoreFromTrash)
+	engine.DELETE("/trash/:id", middleware.IdParam(), ctrl.DeletePermanently)
 }
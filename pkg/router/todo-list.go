@@ -0,0 +1,23 @@
+package router
+
+import (
+	"todo-list-service/pkg/controller"
+	"todo-list-service/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AttachTodoListRoutes(engine gin.IRoutes, ctrl *controller.TodoListController) {
+	engine.GET("/lists", ctrl.FindAll)
+	engine.GET("/lists/:id", middleware.IdParam(), ctrl.FindOneById)
+	engine.GET("/lists/:id/todo", middleware.IdParam(), ctrl.FindItems)
+
+	engine.POST("/lists", ctrl.Create)
+	engine.POST("/lists/:id/todo", middleware.IdParam(), ctrl.MoveItems)
+	engine.POST("/lists/:id/archive", middleware.IdParam(), ctrl.Archive)
+	engine.POST("/lists/:id/unarchive", middleware.IdParam(), ctrl.Unarchive)
+
+	engine.PUT("/lists/:id", middleware.IdParam(), ctrl.UpdateByID)
+
+	engine.DELETE("/lists/:id", middleware.IdParam(), ctrl.DeleteOneById)
+}
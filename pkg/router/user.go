@@ -0,0 +1,16 @@
+package router
+
+import (
+	"todo-list-service/pkg/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AttachUserRoutes(engine gin.IRoutes, ctrl *controller.UserController) {
+	engine.GET("/users/me", ctrl.Me)
+}
+
+// AttachRegistrationRoutes lets anyone register a user, which is only needed when users are identified by the user header
+func AttachRegistrationRoutes(engine gin.IRoutes, ctrl *controller.UserController) {
+	engine.POST("/users", ctrl.Create)
+}
@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// not parallel, NewProvider replaces the global provider and propagator
+func TestNewProvider(t *testing.T) {
+	remote := func(flags string) context.Context {
+		carrier := propagation.MapCarrier{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-" + flags}
+		return propagation.TraceContext{}.Extract(context.Background(), carrier)
+	}
+
+	t.Run("Continues the trace of the caller", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		provider := NewProvider(exporter, "test", 0, time.Hour)
+		defer provider.Shutdown(context.Background())
+
+		_, span := provider.Tracer("test").Start(remote("01"), "span")
+		span.End()
+		provider.ForceFlush(context.Background())
+
+		spans := exporter.GetSpans()
+		if len(spans) != 1 || spans[0].Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || spans[0].Parent.SpanID().String() != "00f067aa0ba902b7" {
+			t.Fatalf("NewProvider() exported %+v, want a child of the sampled caller", spans)
+		}
+		if service, _ := spans[0].Resource.Set().Value("service.name"); service.AsString() != "test" {
+			t.Errorf("NewProvider() service = %q, want %q", service.AsString(), "test")
+		}
+	})
+
+	t.Run("Keeps the decision of the caller", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		provider := NewProvider(exporter, "test", 1, time.Hour)
+		defer provider.Shutdown(context.Background())
+
+		_, span := provider.Tracer("test").Start(remote("00"), "span")
+		span.End()
+		provider.ForceFlush(context.Background())
+
+		if spans := exporter.GetSpans(); len(spans) != 0 {
+			t.Errorf("NewProvider() exported %+v, want nothing for a caller that didn't sample", spans)
+		}
+	})
+
+	t.Run("Samples the ratio of new traces", func(t *testing.T) {
+		exporter := tracetest.NewInMemoryExporter()
+		provider := NewProvider(exporter, "test", 0, time.Hour)
+		defer provider.Shutdown(context.Background())
+
+		_, span := provider.Tracer("test").Start(context.Background(), "span")
+		span.End()
+		provider.ForceFlush(context.Background())
+
+		if spans := exporter.GetSpans(); len(spans) != 0 {
+			t.Errorf("NewProvider() exported %+v, want nothing with a ratio of 0", spans)
+		}
+	})
+}
+
+func TestNewOTLPExporter(t *testing.T) {
+	t.Parallel()
+
+	paths := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.Path
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLPExporter(context.Background(), server.URL+"/")
+	if err != nil {
+		t.Fatalf("NewOTLPExporter() error = %v", err)
+	}
+	stub := tracetest.SpanStub{Name: "span", SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})}
+	exporter.ExportSpans(context.Background(), tracetest.SpanStubs{stub}.Snapshots())
+	exporter.Shutdown(context.Background())
+
+	if path := <-paths; path != "/v1/traces" {
+		t.Errorf("NewOTLPExporter() posted to %q, want %q", path, "/v1/traces")
+	}
+}
+
+func TestNewFileExporter(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "traces.jsonl")
+	exporter, err := NewFileExporter(path)
+	if err != nil {
+		t.Fatalf("NewFileExporter() error = %v", err)
+	}
+	stub := tracetest.SpanStub{Name: "span", SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{1}, SpanID: trace.SpanID{2}})}
+	exporter.ExportSpans(context.Background(), tracetest.SpanStubs{stub}.Snapshots())
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatalf("NewFileExporter() shutdown error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	var span map[string]any
+	if err := json.Unmarshal(data, &span); err != nil || span["Name"] != "span" {
+		t.Errorf("NewFileExporter() wrote %s, want the span as JSON", data)
+	}
+}
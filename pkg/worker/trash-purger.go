@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+	"todo-list-service/pkg/db"
+)
+
+// the amount of items read from the trash at once while purging
+const purgeBatchSize = 100
+
+// TrashPurger permanently deletes the items that have been in the trash for longer than Retention
+type TrashPurger struct {
+	TodoItemDbHandler db.TodoItemDbHandlerInterface
+	Retention         time.Duration
+	Interval          time.Duration
+}
+
+// Run purges the trash right away and then every Interval, until the context is done
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := p.Purge(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "failed to purge the trash", "error", err)
+		}
+		if purged > 0 {
+			slog.InfoContext(ctx, "purged the trash", "items", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes the expired items once and returns how many were deleted
+func (p *TrashPurger) Purge(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-p.Retention)
+	filter := db.TodoItemFilter{Trashed: true, DeletedBefore: &cutoff}
+	page := db.PageOptions{Limit: purgeBatchSize}
+
+	purged := 0
+	for {
+		items, err := p.TodoItemDbHandler.FindAll(ctx, filter, page)
+		if err != nil {
+			return purged, err
+		}
+
+		for _, item := range items.Items {
+			// a changed version means the item was restored in the meantime
+			err := p.TodoItemDbHandler.DeleteOneById(ctx, item.Id, item.Version)
+			if errors.Is(err, db.ErrVersionConflict) {
+				continue
+			}
+			if err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if !items.Truncated {
+			return purged, nil
+		}
+		page.After = items.Next
+	}
+}
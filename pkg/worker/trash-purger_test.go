@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+	"todo-list-service/pkg/db"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestTrashPurger_Purge(t *testing.T) {
+	ctx := context.Background()
+	h := &db.TodoItemMemoryDbHandler{}
+
+	insert := func(trash bool) primitive.ObjectID {
+		id, err := h.InsertOne(ctx, &db.TodoItemDb{Title: "item", DueDate: time.Now()})
+		if err != nil {
+			t.Fatalf("TodoItemMemoryDbHandler.InsertOne() error = %v", err)
+		}
+		if trash {
+			if _, err := h.TrashOneById(ctx, id, db.AnyVersion); err != nil {
+				t.Fatalf("TodoItemMemoryDbHandler.TrashOneById() error = %v", err)
+			}
+		}
+		return id
+	}
+
+	live := insert(false)
+	trashed := []primitive.ObjectID{}
+	for i := 0; i < purgeBatchSize+5; i++ {
+		trashed = append(trashed, insert(true))
+	}
+
+	t.Run("keeps items within the retention", func(t *testing.T) {
+		p := &TrashPurger{TodoItemDbHandler: h, Retention: time.Hour}
+		purged, err := p.Purge(ctx)
+		if err != nil || purged != 0 {
+			t.Errorf("TrashPurger.Purge() = %v, %v, want 0", purged, err)
+		}
+	})
+
+	t.Run("deletes expired items only", func(t *testing.T) {
+		time.Sleep(2 * time.Millisecond)
+		p := &TrashPurger{TodoItemDbHandler: h, Retention: time.Millisecond}
+		purged, err := p.Purge(ctx)
+		if err != nil || purged != len(trashed) {
+			t.Errorf("TrashPurger.Purge() = %v, %v, want %v", purged, err, len(trashed))
+		}
+
+		for _, id := range trashed {
+			if item, _ := h.FindOneById(ctx, id); item != nil {
+				t.Errorf("TrashPurger.Purge() left %v", id.Hex())
+			}
+		}
+		if item, _ := h.FindOneById(ctx, live); item == nil {
+			t.Errorf("TrashPurger.Purge() deleted an item that is not in the trash")
+		}
+	})
+}